@@ -0,0 +1,231 @@
+package lyveapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AccessChange describes actions which became allowed or denied on a
+// resource pattern.
+type AccessChange struct {
+	// Resource is the resource pattern the actions apply to, such as
+	// "arn:aws:s3:::logs-*/*", or "*" for account-level actions.
+	Resource string `json:"resource"`
+	// Actions are the affected S3 actions, sorted.
+	Actions []string `json:"actions"`
+}
+
+// AccessDiff is the difference in effective access between two permissions,
+// as computed by DiffAccess.
+type AccessDiff struct {
+	// Gained lists the access allowed after the change but not before.
+	Gained []AccessChange `json:"gained"`
+	// Lost lists the access allowed before the change but not after.
+	Lost []AccessChange `json:"lost"`
+}
+
+// Empty returns true when the effective access did not change.
+func (d *AccessDiff) Empty() bool {
+	return len(d.Gained) == 0 && len(d.Lost) == 0
+}
+
+// String renders the diff as a human-readable report, with one line per
+// resource pattern listing the affected actions. Lines describing gained
+// access start with "+" and those describing lost access with "-".
+func (d *AccessDiff) String() string {
+	if d.Empty() {
+		return "no change in effective access\n"
+	}
+
+	var sb strings.Builder
+	for _, c := range d.Gained {
+		fmt.Fprintf(&sb, "+ %s: %s\n", c.Resource, strings.Join(c.Actions, ", "))
+	}
+	for _, c := range d.Lost {
+		fmt.Fprintf(&sb, "- %s: %s\n", c.Resource, strings.Join(c.Actions, ", "))
+	}
+	return sb.String()
+}
+
+// JSON returns the diff encoded as indented JSON.
+func (d *AccessDiff) JSON() (string, error) {
+	out := *d
+	if out.Gained == nil {
+		out.Gained = []AccessChange{}
+	}
+	if out.Lost == nil {
+		out.Lost = []AccessChange{}
+	}
+
+	b, err := json.MarshalIndent(&out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// accessProbe is a resource at which access is sampled by DiffAccess.
+type accessProbe struct {
+	pattern string        // the resource pattern reported in the diff
+	bucket  string        // the sample bucket, empty for the account
+	key     string        // the sample key, empty for a bucket
+	scope   s3ActionScope // the scope of the actions to sample
+}
+
+// probeWildcard replaces wildcards in resource patterns to form sample
+// resources. It is not a valid character in bucket names, which avoids
+// accidentally matching a literal pattern of the other permission.
+const probeWildcard = "_"
+
+// DiffAccess computes the difference in effective access between two versions
+// of a permission, of any types, for example to review a change before calling
+// UpdatePermission. A nil permission grants no access, which makes it
+// possible to diff the creation or deletion of a permission.
+//
+// The difference is computed by evaluating both permissions, as Evaluate
+// does, at a sample resource for each resource pattern named by either
+// permission, for each known S3 action applicable to the resource and each
+// action named explicitly by either permission. A wildcard in a pattern is
+// sampled at a single value, so when one permission narrows a pattern of the
+// other, for example "logs-*" to "logs-2023*", the change is reported against
+// the broader pattern. Requests are evaluated without request context, so
+// statements with conditions or policy variables apply only when they do so
+// without context values.
+func DiffAccess(before, after *Permission) (*AccessDiff, error) {
+	var perms [2][]Permission
+	var probes []accessProbe
+	seen := map[accessProbe]bool{}
+	explicit := map[string]bool{}
+
+	for i, p := range []*Permission{before, after} {
+		if p == nil {
+			continue
+		}
+
+		doc, err := p.ToPolicyDocument()
+		if err != nil {
+			return nil, fmt.Errorf("permission %s: %w", permissionLabel(p), err)
+		}
+		perms[i] = []Permission{*p}
+
+		for _, s := range doc.Statement {
+			for _, r := range append(append([]string(nil), s.Resource...),
+				s.NotResource...) {
+				for _, probe := range resourceProbes(r) {
+					if !seen[probe] {
+						seen[probe] = true
+						probes = append(probes, probe)
+					}
+				}
+			}
+
+			for _, a := range append(append([]string(nil), s.Action...),
+				s.NotAction...) {
+				if !strings.ContainsAny(a, "*?") {
+					explicit[canonicalActionName(a)] = true
+				}
+			}
+		}
+	}
+
+	gained := map[string][]string{}
+	lost := map[string][]string{}
+
+	for _, probe := range probes {
+		for _, action := range probeActions(probe.scope, explicit) {
+			req := &AccessRequest{
+				Action: action,
+				Bucket: probe.bucket,
+				Key:    probe.key,
+			}
+
+			var allowed [2]bool
+			for i := range perms {
+				d, err := Evaluate(perms[i], req)
+				if err != nil {
+					return nil, err
+				}
+				allowed[i] = d.Allowed
+			}
+
+			switch {
+			case allowed[1] && !allowed[0]:
+				gained[probe.pattern] = append(gained[probe.pattern], action)
+			case allowed[0] && !allowed[1]:
+				lost[probe.pattern] = append(lost[probe.pattern], action)
+			}
+		}
+	}
+
+	return &AccessDiff{
+		Gained: accessChanges(gained),
+		Lost:   accessChanges(lost),
+	}, nil
+}
+
+// resourceProbes returns the sample resources for a resource pattern. A
+// pattern with a wildcard in the bucket name and no key, such as
+// "arn:aws:s3:::logs-*", also matches objects and is sampled as both a bucket
+// and an object.
+func resourceProbes(pattern string) []accessProbe {
+	if pattern == "*" {
+		return []accessProbe{
+			{pattern: "*", scope: scopeService},
+			{pattern: BucketARN("*"), bucket: probeWildcard, scope: scopeBucket},
+			{pattern: ObjectARN("*", "*"), bucket: probeWildcard,
+				key: probeWildcard, scope: scopeObject},
+		}
+	}
+
+	name, ok := strings.CutPrefix(pattern, s3ArnPrefix)
+	if !ok {
+		return nil
+	}
+
+	sample := func(s string) string {
+		return strings.NewReplacer("*", probeWildcard, "?", probeWildcard).
+			Replace(s)
+	}
+
+	bucket, key, hasKey := strings.Cut(name, "/")
+	if hasKey {
+		return []accessProbe{{pattern: pattern, bucket: sample(bucket),
+			key: sample(key), scope: scopeObject}}
+	}
+
+	probes := []accessProbe{{pattern: pattern, bucket: sample(bucket),
+		scope: scopeBucket}}
+	if strings.HasSuffix(bucket, "*") {
+		probes = append(probes, accessProbe{pattern: pattern + "/*",
+			bucket: sample(bucket), key: probeWildcard, scope: scopeObject})
+	}
+	return probes
+}
+
+// probeActions returns the known actions of the given scope together with the
+// explicitly named actions which are not known, sorted.
+func probeActions(scope s3ActionScope, explicit map[string]bool) []string {
+	var actions []string
+	for a, s := range s3Actions {
+		if s == scope {
+			actions = append(actions, a)
+		}
+	}
+	for a := range explicit {
+		if _, known := s3Actions[a]; !known {
+			actions = append(actions, a)
+		}
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+func accessChanges(m map[string][]string) []AccessChange {
+	var changes []AccessChange
+	for _, r := range sortedKeys(m) {
+		changes = append(changes, AccessChange{Resource: r, Actions: m[r]})
+	}
+	return changes
+}
@@ -0,0 +1,98 @@
+package lyveapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffAccess(t *testing.T) {
+	t.Parallel()
+
+	before := &Permission{
+		Name:    "logs",
+		Type:    BucketPrefix,
+		Prefix:  "logs-",
+		Actions: ReadOnly,
+	}
+
+	after := &Permission{
+		Name: "logs",
+		Type: Policy,
+		Policy: `{"Version":"2012-10-17","Statement":[
+			{"Effect":"Allow","Action":["s3:GetObject","s3:PutObject"],
+			 "Resource":"arn:aws:s3:::logs-*/*"},
+			{"Effect":"Allow","Action":"s3:ListBucket","Resource":"arn:aws:s3:::logs-*"}]}`,
+	}
+
+	diff, err := DiffAccess(before, after)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(diff.Gained, []AccessChange{
+		{Resource: "arn:aws:s3:::logs-*/*", Actions: []string{"s3:PutObject"}},
+	}) {
+		t.Errorf("Unexpected gained access: %+v", diff.Gained)
+	}
+
+	if len(diff.Lost) != 2 {
+		t.Fatalf("Expected lost access on two patterns: %+v", diff.Lost)
+	}
+	for _, c := range diff.Lost {
+		for _, a := range c.Actions {
+			if a == "s3:GetObject" || a == "s3:ListBucket" {
+				t.Errorf("Unexpected lost action %s on %s", a, c.Resource)
+			}
+		}
+	}
+
+	if !strings.HasPrefix(diff.String(), "+ arn:aws:s3:::logs-*/*: s3:PutObject\n") {
+		t.Errorf("Unexpected report:\n%s", diff)
+	}
+
+	s, err := diff.JSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var decoded AccessDiff
+	if err = json.Unmarshal([]byte(s), &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, diff) {
+		t.Errorf("JSON report did not round trip: %s", s)
+	}
+}
+
+func TestDiffAccessUnchanged(t *testing.T) {
+	t.Parallel()
+
+	p := &Permission{
+		Type:    BucketNames,
+		Buckets: []string{"alpha"},
+		Actions: AllOperations,
+	}
+
+	diff, err := DiffAccess(p, p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !diff.Empty() {
+		t.Errorf("Expected no change: %s", diff)
+	}
+
+	s, _ := diff.JSON()
+	if s != "{\n  \"gained\": [],\n  \"lost\": []\n}" {
+		t.Errorf("Unexpected JSON report: %s", s)
+	}
+
+	diff, err = DiffAccess(nil, p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(diff.Gained) != 2 || len(diff.Lost) != 0 {
+		t.Errorf("Expected access gained on the bucket and its objects: %s", diff)
+	}
+}
@@ -1,6 +1,7 @@
 package lyveapi
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -8,9 +9,23 @@ import (
 
 // CreateServiceAccount creates an account described by the createReq parameter
 // returns a nil and an error if decoding of the response fails, otherwise a
-// decoded object and nil error is returned.
-func (client *Client) CreateServiceAccount(
-	createReq *CreateServiceAcctReq) (*CreateServiceAcctResp, error) {
+// decoded object and nil error is returned. Pass WaitUntilReady to block until
+// the new account is ready.
+func (client *Client) CreateServiceAccount(createReq *CreateServiceAcctReq,
+	opts ...CreateOption) (*CreateServiceAcctResp, error) {
+	return client.CreateServiceAccountWithContext(
+		context.Background(), createReq, opts...)
+}
+
+// CreateServiceAccountWithContext is functionally identical to
+// CreateServiceAccount(...) with the only difference being the context
+// parameter as the first argument, which is passed to the underlying HTTP
+// request.
+func (client *Client) CreateServiceAccountWithContext(ctx context.Context,
+	createReq *CreateServiceAcctReq,
+	opts ...CreateOption) (*CreateServiceAcctResp, error) {
+	const op = "CreateServiceAccount"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/service-accounts"
 	token := client.token
@@ -20,26 +35,35 @@ func (client *Client) CreateServiceAccount(
 	var buf []byte
 	var rdr io.ReadCloser
 
+	if err = createReq.Validate(); err != nil {
+		return nil, opError(op, http.MethodPost, endpoint, err)
+	}
+
 	if buf, err = json.Marshal(createReq); err != nil {
-		return nil, err
+		return nil, opError(op, http.MethodPost, endpoint, err)
 	}
 
-	if rdr, err = apiRequestAuthenticated(
-		token, http.MethodPost, endpoint, buf); err != nil {
+	if rdr, err = apiRequestAuthenticatedWithContext(ctx,
+		op, token, http.MethodPost, endpoint, buf); err != nil {
 		return nil, err
 	}
 
 	defer rdr.Close()
 
-	respBodyDecoder := json.NewDecoder(rdr)
-
 	svcAcctResp := &CreateServiceAcctResp{}
 	// This is where we fail when the v2/service-accounts/ request URI is
 	// missing a trailing "/".
-	if err := respBodyDecoder.Decode(svcAcctResp); err != nil {
+	if err := decodeResponseBody(rdr, svcAcctResp); err != nil {
 		return nil, err
 	}
 
+	if o := newCreateOptions(opts); o.wait {
+		if _, err := client.waitServiceAccountReady(
+			o.waitCtx, svcAcctResp.Id, o.waitOpts, true); err != nil {
+			return svcAcctResp, opError(op, http.MethodPost, endpoint, err)
+		}
+	}
+
 	return svcAcctResp, nil
 }
 
@@ -47,6 +71,17 @@ func (client *Client) CreateServiceAccount(
 // A successful request will result in service account listing and nil error,
 // whereas a nil and an error is returned on failure.
 func (client *Client) ListServiceAccounts() (*ServiceAcctList, error) {
+	return client.ListServiceAccountsWithContext(context.Background())
+}
+
+// ListServiceAccountsWithContext is functionally identical to
+// ListServiceAccounts(...) with the only difference being the context
+// parameter as the first argument, which is passed to the underlying HTTP
+// request.
+func (client *Client) ListServiceAccountsWithContext(
+	ctx context.Context) (*ServiceAcctList, error) {
+	const op = "ListServiceAccounts"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/service-accounts"
 	token := client.token
@@ -55,16 +90,15 @@ func (client *Client) ListServiceAccounts() (*ServiceAcctList, error) {
 	var err error
 	var rdr io.ReadCloser
 
-	if rdr, err = apiRequestAuthenticated(
-		token, http.MethodGet, endpoint, nil); err != nil {
+	if rdr, err = apiRequestAuthenticatedWithContext(ctx,
+		op, token, http.MethodGet, endpoint, nil); err != nil {
 		return nil, err
 	}
 
 	defer rdr.Close()
 
 	svcAccts := &ServiceAcctList{}
-	respBodyDecoder := json.NewDecoder(rdr)
-	if err := respBodyDecoder.Decode(svcAccts); err != nil {
+	if err := decodeResponseBody(rdr, svcAccts); err != nil {
 		return nil, err
 	}
 
@@ -75,6 +109,16 @@ func (client *Client) ListServiceAccounts() (*ServiceAcctList, error) {
 // found. A successful request will result in a account details and a nil error,
 // whereas a nil and an error is returned on failure.
 func (client *Client) GetServiceAccount(svcAcctId string) (*ServiceAcct, error) {
+	return client.GetServiceAccountWithContext(context.Background(), svcAcctId)
+}
+
+// GetServiceAccountWithContext is functionally identical to
+// GetServiceAccount(...) with the only difference being the context parameter
+// as the first argument, which is passed to the underlying HTTP request.
+func (client *Client) GetServiceAccountWithContext(
+	ctx context.Context, svcAcctId string) (*ServiceAcct, error) {
+	const op = "GetServiceAccount"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/service-accounts"
 	url := endpoint + "/" + svcAcctId
@@ -84,16 +128,15 @@ func (client *Client) GetServiceAccount(svcAcctId string) (*ServiceAcct, error)
 	var err error
 	var rdr io.ReadCloser
 
-	if rdr, err = apiRequestAuthenticated(
-		token, http.MethodGet, url, nil); err != nil {
+	if rdr, err = apiRequestAuthenticatedWithContext(ctx,
+		op, token, http.MethodGet, url, nil); err != nil {
 		return nil, err
 	}
 
 	defer rdr.Close()
 
 	var acctInfo = &ServiceAcct{}
-	respBodyDecoder := json.NewDecoder(rdr)
-	if err := respBodyDecoder.Decode(acctInfo); err != nil {
+	if err := decodeResponseBody(rdr, acctInfo); err != nil {
 		return nil, err
 	}
 	return acctInfo, nil
@@ -101,9 +144,13 @@ func (client *Client) GetServiceAccount(svcAcctId string) (*ServiceAcct, error)
 
 // UpdateServiceAccount updates an existing service account with changed
 // settings in updatesReq and returns a nil and an error if decoding of the
-// response fails, otherwise a decoded object and nil error is returned.
-func (client *Client) UpdateServiceAccount(
-	svcAcctId string, updatesReq *ServiceAcct) error {
+// response fails, otherwise a decoded object and nil error is returned. With
+// the IfUnchanged option, the update is only written if the account has not
+// changed since it was fetched.
+func (client *Client) UpdateServiceAccount(svcAcctId string,
+	updatesReq *ServiceAcct, opts ...UpdateOption) error {
+	const op = "UpdateServiceAccount"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/service-accounts"
 	url := endpoint + "/" + svcAcctId
@@ -114,12 +161,21 @@ func (client *Client) UpdateServiceAccount(
 	var rdr io.ReadCloser
 	var data []byte
 
-	if data, err = json.Marshal(updatesReq); err != nil {
+	if err = updatesReq.Validate(); err != nil {
+		return opError(op, http.MethodPut, url, err)
+	}
+
+	if err = client.checkServiceAccountUnchanged(
+		op, url, svcAcctId, newUpdateOptions(opts)); err != nil {
 		return err
 	}
 
+	if data, err = json.Marshal(updatesReq); err != nil {
+		return opError(op, http.MethodPut, url, err)
+	}
+
 	if rdr, err = apiRequestAuthenticated(
-		token, http.MethodPut, url, data); err != nil {
+		op, token, http.MethodPut, url, data); err != nil {
 		return err
 	}
 
@@ -137,6 +193,8 @@ func (client *Client) UpdateServiceAccount(
 // account Id. A successful request will return a nil, whereas an error is
 // returned if no such account could be found or some other error occurs.
 func (client *Client) EnableServiceAccount(svcAcctId string) error {
+	const op = "EnableServiceAccount"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/service-accounts"
 	url := endpoint + "/" + svcAcctId + "/enabled"
@@ -146,7 +204,8 @@ func (client *Client) EnableServiceAccount(svcAcctId string) error {
 	var err error
 	var rdr io.ReadCloser
 
-	if rdr, err = apiRequestAuthenticated(token, http.MethodPut, url, nil); err != nil {
+	if rdr, err = apiRequestAuthenticated(
+		op, token, http.MethodPut, url, nil); err != nil {
 		return err
 	}
 
@@ -164,6 +223,18 @@ func (client *Client) EnableServiceAccount(svcAcctId string) error {
 // account Id. A successful request will return a nil, whereas an error is
 // returned if no such account could be found or some other error occurs.
 func (client *Client) DisableServiceAccount(svcAcctId string) error {
+	return client.DisableServiceAccountWithContext(
+		context.Background(), svcAcctId)
+}
+
+// DisableServiceAccountWithContext is functionally identical to
+// DisableServiceAccount(...) with the only difference being the context
+// parameter as the first argument, which is passed to the underlying HTTP
+// request.
+func (client *Client) DisableServiceAccountWithContext(
+	ctx context.Context, svcAcctId string) error {
+	const op = "DisableServiceAccount"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/service-accounts"
 	url := endpoint + "/" + svcAcctId + "/enabled"
@@ -173,7 +244,8 @@ func (client *Client) DisableServiceAccount(svcAcctId string) error {
 	var err error
 	var rdr io.ReadCloser
 
-	if rdr, err = apiRequestAuthenticated(token, http.MethodDelete, url, nil); err != nil {
+	if rdr, err = apiRequestAuthenticatedWithContext(ctx,
+		op, token, http.MethodDelete, url, nil); err != nil {
 		return err
 	}
 
@@ -191,6 +263,18 @@ func (client *Client) DisableServiceAccount(svcAcctId string) error {
 // account Id. A successful request will return a nil, whereas an error is
 // returned if no such account could be found.
 func (client *Client) DeleteServiceAccount(svcAcctId string) error {
+	return client.DeleteServiceAccountWithContext(
+		context.Background(), svcAcctId)
+}
+
+// DeleteServiceAccountWithContext is functionally identical to
+// DeleteServiceAccount(...) with the only difference being the context
+// parameter as the first argument, which is passed to the underlying HTTP
+// request.
+func (client *Client) DeleteServiceAccountWithContext(
+	ctx context.Context, svcAcctId string) error {
+	const op = "DeleteServiceAccount"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/service-accounts"
 	url := endpoint + "/" + svcAcctId
@@ -200,7 +284,8 @@ func (client *Client) DeleteServiceAccount(svcAcctId string) error {
 	var err error
 	var rdr io.ReadCloser
 
-	if rdr, err = apiRequestAuthenticated(token, http.MethodDelete, url, nil); err != nil {
+	if rdr, err = apiRequestAuthenticatedWithContext(ctx,
+		op, token, http.MethodDelete, url, nil); err != nil {
 		return err
 	}
 
@@ -0,0 +1,147 @@
+package lyveapi
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrConcurrentModification is matched by errors.Is when an object changed
+// while it was being modified, which usually means another administrator
+// modified it at the same time.
+var ErrConcurrentModification = errors.New("object was modified concurrently")
+
+// attachAttempts is the number of times the fetch/modify/verify cycle of
+// AttachPermissions and DetachPermissions is started when the account changes
+// before it is written.
+const attachAttempts = 3
+
+// PermissionSetConflictError is returned by AttachPermissions and
+// DetachPermissions when the permissions of a service account changed while
+// they were being modified.
+type PermissionSetConflictError struct {
+	// ServiceAcctId is the ID of the service account.
+	ServiceAcctId string
+	// Expected is the set of permission IDs the account should have.
+	Expected []string
+	// Actual is the set of permission IDs the account was found to have.
+	Actual []string
+}
+
+func (e *PermissionSetConflictError) Error() string {
+	return "permissions of service account " + e.ServiceAcctId +
+		" were modified concurrently; expected [" +
+		strings.Join(e.Expected, ", ") + "], found [" +
+		strings.Join(e.Actual, ", ") + "]"
+}
+
+// Is reports whether target is ErrConcurrentModification.
+func (e *PermissionSetConflictError) Is(target error) bool {
+	return target == ErrConcurrentModification
+}
+
+// AttachPermissions adds the permissions with the given IDs to the service
+// account, keeping those it already has, and returns the resulting set of
+// permission IDs. See DetachPermissions for how concurrent modification is
+// detected.
+func (client *Client) AttachPermissions(
+	svcAcctId string, permissionIds ...string) ([]string, error) {
+	return client.modifyPermissions(svcAcctId,
+		func(current []string) []string {
+			out := append([]string(nil), current...)
+			have := stringSet(current)
+			for _, id := range permissionIds {
+				if !have[id] {
+					have[id] = true
+					out = append(out, id)
+				}
+			}
+			return out
+		})
+}
+
+// DetachPermissions removes the permissions with the given IDs from the
+// service account and returns the resulting set of permission IDs. IDs which
+// are not attached are ignored. A *ValidationError wrapping
+// ErrNoPermissionsProvided is returned if no permissions would remain.
+//
+// Rather than replacing the whole account, as UpdateServiceAccount does, the
+// account is fetched and only its permissions are written back, then it is
+// fetched again to verify the result. If the permissions of the account change between the first
+// fetch and the write, the cycle is restarted, up to three times in total. If
+// they still differ, or differ from the expected set after the write, a
+// *PermissionSetConflictError is returned.
+func (client *Client) DetachPermissions(
+	svcAcctId string, permissionIds ...string) ([]string, error) {
+	remove := stringSet(permissionIds)
+	return client.modifyPermissions(svcAcctId,
+		func(current []string) []string {
+			var out []string
+			for _, id := range current {
+				if !remove[id] {
+					out = append(out, id)
+				}
+			}
+			return out
+		})
+}
+
+// modifyPermissions implements the fetch/modify/verify cycle of
+// AttachPermissions and DetachPermissions.
+func (client *Client) modifyPermissions(svcAcctId string,
+	modify func(current []string) []string) ([]string, error) {
+	var conflict error
+	for attempt := 0; attempt < attachAttempts; attempt++ {
+		acct, err := client.GetServiceAccount(svcAcctId)
+		if err != nil {
+			return nil, err
+		}
+
+		desired := modify(acct.Permissions)
+		if setsEqual(stringSet(desired), stringSet(acct.Permissions)) {
+			return acct.Permissions, nil
+		}
+
+		if len(desired) == 0 {
+			v := &validator{object: "ServiceAcct"}
+			v.addErr("permissions", "must not be empty",
+				ErrNoPermissionsProvided)
+			return nil, v.err()
+		}
+
+		// Re-read immediately before writing, since the API offers no
+		// conditional update.
+		latest, err := client.GetServiceAccount(svcAcctId)
+		if err != nil {
+			return nil, err
+		}
+
+		if !setsEqual(stringSet(latest.Permissions),
+			stringSet(acct.Permissions)) {
+			conflict = &PermissionSetConflictError{
+				ServiceAcctId: svcAcctId,
+				Expected:      acct.Permissions,
+				Actual:        latest.Permissions,
+			}
+			continue
+		}
+
+		// Only the permissions are written, and the account is fetched again
+		// to verify the result.
+		verify, err := client.UpdateServiceAccountFields(svcAcctId,
+			&ServiceAcctUpdateReq{Permissions: &desired})
+		if err != nil {
+			return nil, err
+		}
+
+		if !setsEqual(stringSet(verify.Permissions), stringSet(desired)) {
+			return nil, &PermissionSetConflictError{
+				ServiceAcctId: svcAcctId,
+				Expected:      desired,
+				Actual:        verify.Permissions,
+			}
+		}
+		return verify.Permissions, nil
+	}
+
+	return nil, conflict
+}
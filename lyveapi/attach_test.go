@@ -0,0 +1,110 @@
+package lyveapi
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAttachDetachPermissions(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	a := f.addServiceAccount(ServiceAcct{
+		Name: "a", Enabled: true, Permissions: []string{"p1"}})
+
+	perms, err := client.AttachPermissions(a, "p2", "p1", "p3")
+	if err != nil || !reflect.DeepEqual(perms, []string{"p1", "p2", "p3"}) {
+		t.Fatalf("Unexpected result: %v, %v", perms, err)
+	}
+
+	if !f.serviceAccount(a).Enabled {
+		t.Error("Expected other fields of the account to be kept")
+	}
+
+	// Another administrator changes the description just before the write,
+	// which only sends the permissions.
+	f.mtx.Lock()
+	f.before = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodPut {
+			f.accts[a].Description = "concurrent"
+			f.before = nil
+		}
+		return true
+	}
+	f.mtx.Unlock()
+
+	if _, err = client.AttachPermissions(a, "p4"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if f.serviceAccount(a).Description != "concurrent" {
+		t.Error("Expected the concurrent change to be kept")
+	}
+
+	perms, err = client.DetachPermissions(a, "p1", "p4", "p5")
+	if err != nil || !reflect.DeepEqual(perms, []string{"p2", "p3"}) {
+		t.Fatalf("Unexpected result: %v, %v", perms, err)
+	}
+
+	// Nothing to do, so nothing is written.
+	puts := f.count(http.MethodPut, "/service-accounts")
+	if _, err = client.AttachPermissions(a, "p2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := f.count(http.MethodPut, "/service-accounts"); n != puts {
+		t.Error("Expected no update when the set does not change")
+	}
+
+	_, err = client.DetachPermissions(a, "p2", "p3")
+	if !errors.Is(err, ErrNoPermissionsProvided) {
+		t.Errorf("Expected a validation error; got %v", err)
+	}
+}
+
+func TestAttachPermissionsConcurrentModification(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	a := f.addServiceAccount(ServiceAcct{Name: "a", Permissions: []string{"p1"}})
+
+	// Another administrator attaches p9 between the first read and the
+	// re-read before writing, so the cycle is restarted.
+	gets := 0
+	f.mtx.Lock()
+	f.before = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodGet {
+			if gets++; gets == 2 {
+				f.accts[a].Permissions = append(f.accts[a].Permissions, "p9")
+			}
+		}
+		return true
+	}
+	f.mtx.Unlock()
+
+	perms, err := client.AttachPermissions(a, "p2")
+	if err != nil || !reflect.DeepEqual(perms, []string{"p1", "p9", "p2"}) {
+		t.Fatalf("Expected the concurrent change to be kept: %v, %v", perms, err)
+	}
+
+	// Another administrator replaces the permissions after the write, which
+	// is detected when verifying.
+	f.mtx.Lock()
+	f.before = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodPut {
+			f.before = func(w http.ResponseWriter, r *http.Request) bool {
+				f.accts[a].Permissions = []string{"p7"}
+				return true
+			}
+		}
+		return true
+	}
+	f.mtx.Unlock()
+
+	_, err = client.DetachPermissions(a, "p1")
+	var conflict *PermissionSetConflictError
+	if !errors.As(err, &conflict) || !IsConflict(err) ||
+		!reflect.DeepEqual(conflict.Actual, []string{"p7"}) {
+		t.Errorf("Expected a conflict; got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package lyveapi
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// ImportAWSPolicy converts a policy written in the AWS IAM or bucket policy
+// JSON format, such as the contents of a policy file, into a permission of
+// type "policy" with the given name.
+//
+// Elements which Lyve Cloud permissions do not support are removed and
+// reported as findings of warning severity: Principal and NotPrincipal
+// elements, since permissions apply to the service accounts they are attached
+// to, and actions of services other than S3, whether in Action or NotAction.
+// A statement left without any actions is removed altogether, whereas one
+// which only excluded actions of other services applies to all S3 actions.
+// The remaining document is linted, so the findings also report unknown S3
+// actions, unsupported condition keys and similar problems. Statement indices in the findings refer to the statements
+// of the imported document, before any were removed. Use the Err method of the
+// findings to reject imports with problems of a given severity.
+//
+// An error is returned if the data is not a valid policy document, or if
+// nothing remains of it after unsupported elements are removed.
+func ImportAWSPolicy(name string, data []byte) (*Permission, LintFindings, error) {
+	doc, err := ParsePolicyDocument(string(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l := &linter{}
+	out := &PolicyDocument{Version: doc.Version, Id: doc.Id}
+	var origIdx []int
+
+	for i, s := range doc.Statement {
+		if s.Principal != nil || s.NotPrincipal != nil {
+			l.add(LintRulePrincipal, SeverityWarning, i, s.Sid,
+				"removed Principal and NotPrincipal, which are not supported "+
+					"in permission policies")
+			s.Principal, s.NotPrincipal = nil, nil
+		}
+
+		if len(s.Action) > 0 {
+			s.Action = l.removeForeignActions(i, s.Sid, s.Action)
+			if len(s.Action) == 0 {
+				l.add(LintRuleUnknownAction, SeverityWarning, i, s.Sid,
+					"removed statement, which names no S3 actions")
+				continue
+			}
+		}
+
+		// Excluding only actions of other services excludes no S3 action.
+		if len(s.NotAction) > 0 {
+			s.NotAction = l.removeForeignActions(i, s.Sid, s.NotAction)
+			if len(s.NotAction) == 0 {
+				s.NotAction, s.Action = nil, StringList{"s3:*"}
+			}
+		}
+
+		out.Statement = append(out.Statement, s)
+		origIdx = append(origIdx, i)
+	}
+
+	for _, f := range LintPolicy(out) {
+		if f.Statement >= 0 {
+			f.Statement = origIdx[f.Statement]
+		}
+		l.findings = append(l.findings, f)
+	}
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		return l.findings[i].Statement < l.findings[j].Statement
+	})
+
+	if len(out.Statement) == 0 {
+		return nil, l.findings, &ValidationError{
+			Object: "AWS policy",
+			Fields: []*FieldError{{Field: "Statement",
+				Message: "no supported statements remain"}},
+		}
+	}
+
+	perm := &Permission{Name: name}
+	if err = perm.SetPolicyDocument(out); err != nil {
+		return nil, l.findings, err
+	}
+
+	return perm, l.findings, nil
+}
+
+// removeForeignActions returns the actions of S3 and wildcard actions from
+// actions, reporting each action of another service which is removed.
+func (l *linter) removeForeignActions(idx int, sid string,
+	actions StringList) StringList {
+	var out StringList
+	for _, a := range actions {
+		service, _, found := strings.Cut(a, ":")
+		if a != "*" && found && !strings.EqualFold(service, "s3") {
+			l.add(LintRuleUnknownAction, SeverityWarning, idx, sid,
+				"removed action %q of unsupported service %s", a, service)
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// ExportAWSPolicy renders a permission of any type as an AWS IAM policy
+// document, indented for readability. Structured permissions are rendered as
+// their equivalent policy documents, as described by ToPolicyDocument. An
+// empty Version is set to PolicyVersion.
+func ExportAWSPolicy(p *Permission) ([]byte, error) {
+	doc, err := p.ToPolicyDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.Version == "" {
+		doc.Version = PolicyVersion
+	}
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,155 @@
+package lyveapi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestImportAWSPolicy(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`{
+		"Version": "2012-10-17",
+		"Statement": [
+			{
+				"Sid": "ec2",
+				"Effect": "Allow",
+				"Action": "ec2:DescribeInstances",
+				"Resource": "*"
+			},
+			{
+				"Sid": "public-read",
+				"Effect": "Allow",
+				"Principal": "*",
+				"Action": ["s3:GetObject", "sqs:SendMessage"],
+				"Resource": "arn:aws:s3:::website/*"
+			},
+			{
+				"Sid": "typo",
+				"Effect": "Allow",
+				"Action": "s3:GetObjekt",
+				"Resource": "arn:aws:s3:::website/*"
+			}
+		]
+	}`)
+
+	perm, findings, err := ImportAWSPolicy("website", data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if perm.Name != "website" || perm.Type != Policy {
+		t.Errorf("Unexpected permission: %+v", perm)
+	}
+
+	doc, err := perm.PolicyDocument()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(doc.Statement) != 2 || doc.Statement[0].Principal != nil ||
+		len(doc.Statement[0].Action) != 1 {
+		t.Errorf("Unexpected imported policy: %s", perm.Policy)
+	}
+
+	type finding struct {
+		rule string
+		idx  int
+		sev  Severity
+	}
+	expected := []finding{
+		{LintRuleUnknownAction, 0, SeverityWarning},
+		{LintRuleUnknownAction, 0, SeverityWarning},
+		{LintRulePrincipal, 1, SeverityWarning},
+		{LintRuleUnknownAction, 1, SeverityWarning},
+		{LintRuleUnknownAction, 2, SeverityError},
+	}
+
+	if len(findings) != len(expected) {
+		t.Fatalf("Unexpected findings: %v", findings)
+	}
+	for i, e := range expected {
+		f := findings[i]
+		if f.RuleId != e.rule || f.Statement != e.idx || f.Severity != e.sev {
+			t.Errorf("Unexpected finding %d: %s", i, f)
+		}
+	}
+}
+
+func TestImportAWSPolicyNothingSupported(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := ImportAWSPolicy("x", []byte(`{"Statement":{"Effect":"Allow",
+		"Action":"iam:*","Resource":"*"}}`))
+	if !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("Expected a validation error; got %v", err)
+	}
+
+	if _, _, err = ImportAWSPolicy("x", []byte(`not json`)); err == nil {
+		t.Error("Expected an error for an invalid document")
+	}
+}
+
+func TestImportAWSPolicyNotAction(t *testing.T) {
+	t.Parallel()
+
+	perm, findings, err := ImportAWSPolicy("x", []byte(`{"Statement":[
+		{"Effect":"Deny","NotAction":["iam:*","s3:GetObject"],"Resource":"*"},
+		{"Effect":"Allow","NotAction":"ec2:*",
+			"Resource":"arn:aws:s3:::b/*"}]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	doc, err := perm.PolicyDocument()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(doc.Statement) != 2 {
+		t.Fatalf("Unexpected imported policy: %s", perm.Policy)
+	}
+	if s := doc.Statement[0]; len(s.Action) != 0 ||
+		len(s.NotAction) != 1 || s.NotAction[0] != "s3:GetObject" {
+		t.Errorf("Unexpected first statement: %+v", s)
+	}
+	if s := doc.Statement[1]; len(s.NotAction) != 0 ||
+		len(s.Action) != 1 || s.Action[0] != "s3:*" {
+		t.Errorf("Unexpected second statement: %+v", s)
+	}
+
+	if len(findings) != 2 || findings[0].Statement != 0 ||
+		findings[1].Statement != 1 {
+		t.Errorf("Unexpected findings: %v", findings)
+	}
+}
+
+func TestExportAWSPolicy(t *testing.T) {
+	t.Parallel()
+
+	p := &Permission{
+		Name:    "alpha",
+		Type:    BucketNames,
+		Buckets: []string{"alpha"},
+		Actions: ReadOnly,
+	}
+
+	b, err := ExportAWSPolicy(p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	s := string(b)
+	if !strings.HasPrefix(s, "{\n  \"Version\": \"2012-10-17\",") ||
+		!strings.HasSuffix(s, "}\n") {
+		t.Errorf("Unexpected export:\n%s", s)
+	}
+
+	imported, findings, err := ImportAWSPolicy("alpha", b)
+	if err != nil || len(findings) != 0 {
+		t.Fatalf("Unexpected import result: %v %v", findings, err)
+	}
+
+	if equal, _ := PermissionsEquivalent(p, imported); !equal {
+		t.Errorf("Expected the export to round trip: %s", imported.Policy)
+	}
+}
@@ -0,0 +1,363 @@
+package lyveapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBulkConcurrency is the number of requests run at once by bulk
+// operations when BulkOptions.Concurrency is not set.
+const DefaultBulkConcurrency = 4
+
+// DefaultBulkRetryDelay is the delay before the first retry of an item
+// rejected with ErrTooManyRequests, when BulkOptions.RetryDelay is not set.
+const DefaultBulkRetryDelay = time.Second
+
+// BulkOptions controls how bulk operations, such as CreatePermissions, run. A
+// nil *BulkOptions uses the defaults throughout.
+type BulkOptions struct {
+	// Concurrency is the maximum number of requests in flight at once. If
+	// zero, DefaultBulkConcurrency is used.
+	Concurrency int
+	// RateLimit is the maximum number of requests started per second,
+	// including retries. If zero, requests are not rate limited.
+	RateLimit float64
+	// MaxRetries is the number of times an item is retried after the API
+	// rejects it with ErrTooManyRequests. Other failures are never retried,
+	// since the request may have taken effect. A negative value is treated as
+	// zero.
+	MaxRetries int
+	// RetryDelay is the delay before the first retry, which doubles with each
+	// subsequent retry. If zero, DefaultBulkRetryDelay is used.
+	RetryDelay time.Duration
+	// StopOnFirstError stops starting new items once any item fails. Items
+	// which were not started are reported as skipped.
+	StopOnFirstError bool
+}
+
+// BulkResult is the outcome of a single item of a bulk operation.
+type BulkResult[T any] struct {
+	// Index is the position of the item in the input.
+	Index int
+	// Key identifies the item, such as the permission name or ID.
+	Key string
+	// Value is the value returned for the item, if it succeeded.
+	Value T
+	// Err is the error returned for the item, if it failed.
+	Err error
+	// Skipped is true when the item was not started, because an earlier item
+	// failed with StopOnFirstError set or the context was cancelled.
+	Skipped bool
+}
+
+// BulkReport lists the outcome of each item of a bulk operation, in the order
+// of the input.
+type BulkReport[T any] struct {
+	// Op is the name of the bulk operation, such as "CreatePermissions".
+	Op string
+	// Results holds one result per input item.
+	Results []BulkResult[T]
+}
+
+// Succeeded returns the results of the items which succeeded.
+func (r *BulkReport[T]) Succeeded() []BulkResult[T] {
+	var out []BulkResult[T]
+	for _, res := range r.Results {
+		if !res.Skipped && res.Err == nil {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+// Err returns a *BulkError when any item failed or was skipped, otherwise
+// nil.
+func (r *BulkReport[T]) Err() error {
+	e := &BulkError{Op: r.Op, Total: len(r.Results)}
+	for _, res := range r.Results {
+		switch {
+		case res.Skipped:
+			e.Skipped++
+		case res.Err != nil:
+			e.Failures = append(e.Failures,
+				BulkFailure{Index: res.Index, Key: res.Key, Err: res.Err})
+		}
+	}
+
+	if len(e.Failures) == 0 && e.Skipped == 0 {
+		return nil
+	}
+	return e
+}
+
+// BulkFailure describes an item of a bulk operation which failed.
+type BulkFailure struct {
+	Index int
+	Key   string
+	Err   error
+}
+
+// BulkError aggregates the failures of a bulk operation. The errors of the
+// failed items are reachable with errors.Is and errors.As.
+type BulkError struct {
+	// Op is the name of the bulk operation.
+	Op string
+	// Total is the number of items in the operation.
+	Total int
+	// Failures lists the items which failed.
+	Failures []BulkFailure
+	// Skipped is the number of items which were not started.
+	Skipped int
+}
+
+func (e *BulkError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d of %d items failed", e.Op, len(e.Failures), e.Total)
+	if e.Skipped > 0 {
+		fmt.Fprintf(&b, ", %d skipped", e.Skipped)
+	}
+
+	for i, f := range e.Failures {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "%s: %v", f.Key, f.Err)
+	}
+	return b.String()
+}
+
+func (e *BulkError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// rateLimiter spaces the start of requests evenly.
+type rateLimiter struct {
+	mtx      sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the next request may start, or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mtx.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mtx.Unlock()
+
+	return sleepContext(ctx, time.Until(at))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// runBulk runs do for each item on a bounded pool of workers. The context
+// only governs whether items are started; requests already in flight are not
+// interrupted.
+func runBulk[I, T any](ctx context.Context, op string, items []I,
+	key func(I) string, opts *BulkOptions,
+	do func(I) (T, error)) (*BulkReport[T], error) {
+	var o BulkOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultBulkConcurrency
+	}
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = DefaultBulkRetryDelay
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+
+	report := &BulkReport[T]{Op: op, Results: make([]BulkResult[T], len(items))}
+	for i, item := range items {
+		report.Results[i] = BulkResult[T]{Index: i, Key: key(item), Skipped: true}
+	}
+
+	limiter := newRateLimiter(o.RateLimit)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < o.Concurrency && w < len(items); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res := &report.Results[i]
+				delay := o.RetryDelay
+				for attempt := 0; ; attempt++ {
+					if limiter.wait(ctx) != nil {
+						break
+					}
+					res.Skipped = false
+					res.Value, res.Err = do(items[i])
+					if attempt == o.MaxRetries || !rateLimited(res.Err) ||
+						sleepContext(ctx, delay) != nil {
+						break
+					}
+					delay *= 2
+				}
+
+				if res.Err != nil && o.StopOnFirstError {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for i := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return report, report.Err()
+}
+
+// rateLimited returns true when err shows the API rejected a request because
+// too many requests were made.
+func rateLimited(err error) bool {
+	var opErr *OperationError
+	return errors.Is(err, ErrTooManyRequests) ||
+		errors.As(err, &opErr) && opErr.StatusCode == http.StatusTooManyRequests
+}
+
+func permissionName(p *Permission) string { return p.Name }
+
+func serviceAcctReqName(r *CreateServiceAcctReq) string { return r.Name }
+
+func identity(id string) string { return id }
+
+// CreatePermissions creates each of the permissions, running requests
+// concurrently as configured by opts. The report lists the created permission
+// or the error for each request, keyed by permission name. If any item failed
+// or was skipped, the error returned is a *BulkError. Cancelling ctx stops
+// further items from starting.
+func (client *Client) CreatePermissions(ctx context.Context,
+	reqs []*Permission, opts *BulkOptions) (*BulkReport[*Permission], error) {
+	return runBulk(ctx, "CreatePermissions", reqs, permissionName, opts,
+		func(p *Permission) (*Permission, error) {
+			return client.CreatePermission(p)
+		})
+}
+
+// GetPermissions fetches the permissions with the given IDs, as described by
+// CreatePermissions.
+func (client *Client) GetPermissions(ctx context.Context,
+	ids []string, opts *BulkOptions) (*BulkReport[*Permission], error) {
+	return runBulk(ctx, "GetPermissions", ids, identity, opts,
+		func(id string) (*Permission, error) {
+			return client.GetPermissionWithContext(ctx, id)
+		})
+}
+
+// DeletePermissions deletes the permissions with the given IDs, as described
+// by CreatePermissions. Service accounts referencing the permissions are not
+// checked; see DeletePermissionSafely.
+func (client *Client) DeletePermissions(ctx context.Context,
+	ids []string, opts *BulkOptions) (*BulkReport[struct{}], error) {
+	return runBulk(ctx, "DeletePermissions", ids, identity, opts,
+		func(id string) (struct{}, error) {
+			return struct{}{}, client.DeletePermission(id)
+		})
+}
+
+// CreateServiceAccounts creates each of the service accounts, as described by
+// CreatePermissions. The report holds the credentials of each created account,
+// keyed by account name.
+func (client *Client) CreateServiceAccounts(ctx context.Context,
+	reqs []*CreateServiceAcctReq,
+	opts *BulkOptions) (*BulkReport[*CreateServiceAcctResp], error) {
+	return runBulk(ctx, "CreateServiceAccounts", reqs, serviceAcctReqName,
+		opts, func(r *CreateServiceAcctReq) (*CreateServiceAcctResp, error) {
+			return client.CreateServiceAccount(r)
+		})
+}
+
+// GetServiceAccounts fetches the service accounts with the given IDs, as
+// described by CreatePermissions.
+func (client *Client) GetServiceAccounts(ctx context.Context,
+	ids []string, opts *BulkOptions) (*BulkReport[*ServiceAcct], error) {
+	return runBulk(ctx, "GetServiceAccounts", ids, identity, opts,
+		func(id string) (*ServiceAcct, error) {
+			return client.GetServiceAccountWithContext(ctx, id)
+		})
+}
+
+// DeleteServiceAccounts deletes the service accounts with the given IDs, as
+// described by CreatePermissions.
+func (client *Client) DeleteServiceAccounts(ctx context.Context,
+	ids []string, opts *BulkOptions) (*BulkReport[struct{}], error) {
+	return runBulk(ctx, "DeleteServiceAccounts", ids, identity, opts,
+		func(id string) (struct{}, error) {
+			return struct{}{}, client.DeleteServiceAccount(id)
+		})
+}
+
+// EnableServiceAccounts enables the service accounts with the given IDs, as
+// described by CreatePermissions.
+func (client *Client) EnableServiceAccounts(ctx context.Context,
+	ids []string, opts *BulkOptions) (*BulkReport[struct{}], error) {
+	return runBulk(ctx, "EnableServiceAccounts", ids, identity, opts,
+		func(id string) (struct{}, error) {
+			return struct{}{}, client.EnableServiceAccount(id)
+		})
+}
+
+// DisableServiceAccounts disables the service accounts with the given IDs, as
+// described by CreatePermissions.
+func (client *Client) DisableServiceAccounts(ctx context.Context,
+	ids []string, opts *BulkOptions) (*BulkReport[struct{}], error) {
+	return runBulk(ctx, "DisableServiceAccounts", ids, identity, opts,
+		func(id string) (struct{}, error) {
+			return struct{}{}, client.DisableServiceAccount(id)
+		})
+}
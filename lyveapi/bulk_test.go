@@ -0,0 +1,152 @@
+package lyveapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCreatePermissionsBulk(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	f.addPermission(Permission{Name: "perm-3", Type: AllBuckets, Actions: ReadOnly})
+
+	var reqs []*Permission
+	for i := 0; i < 10; i++ {
+		reqs = append(reqs, &Permission{
+			Name: "perm-" + strconv.Itoa(i), Type: AllBuckets, Actions: ReadOnly})
+	}
+
+	report, err := client.CreatePermissions(context.Background(), reqs,
+		&BulkOptions{Concurrency: 3})
+
+	var bulkErr *BulkError
+	if !errors.As(err, &bulkErr) || len(bulkErr.Failures) != 1 ||
+		bulkErr.Failures[0].Key != "perm-3" || !errors.Is(err, ErrPermissionExists) {
+		t.Fatalf("Expected one failure; got %v", err)
+	}
+
+	if len(report.Results) != 10 || len(report.Succeeded()) != 9 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	for i, res := range report.Results {
+		if res.Index != i || res.Key != reqs[i].Name {
+			t.Errorf("Unexpected result order: %+v", res)
+		}
+		if res.Err == nil && res.Value.Name != reqs[i].Name {
+			t.Errorf("Unexpected value: %+v", res.Value)
+		}
+		if res.Err == nil && f.permission(res.Value.Id) == nil {
+			t.Errorf("Expected %s to be stored", res.Key)
+		}
+	}
+	if n := f.count(http.MethodPost, "/permissions"); n != 10 {
+		t.Errorf("Expected one create request per item; got %d", n)
+	}
+}
+
+func TestBulkStopOnFirstError(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+
+	ids := []string{"missing-0", "missing-1", "missing-2", "missing-3"}
+	report, err := client.DeletePermissions(context.Background(), ids,
+		&BulkOptions{Concurrency: 1, StopOnFirstError: true})
+
+	var bulkErr *BulkError
+	if !errors.As(err, &bulkErr) || len(bulkErr.Failures) != 1 ||
+		bulkErr.Skipped != 3 {
+		t.Fatalf("Expected one failure and three skipped items; got %v", err)
+	}
+	if !report.Results[3].Skipped || !IsNotFound(report.Results[0].Err) {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if n := f.count(http.MethodDelete, "/permissions/"); n != 1 {
+		t.Errorf("Expected skipped items not to be sent; got %d deletes", n)
+	}
+}
+
+func TestBulkRateLimitAndRetry(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	a := f.addServiceAccount(ServiceAcct{Name: "a"})
+	b := f.addServiceAccount(ServiceAcct{Name: "b"})
+
+	var throttled int32
+	f.mtx.Lock()
+	f.before = func(w http.ResponseWriter, r *http.Request) bool {
+		if atomic.AddInt32(&throttled, 1) == 1 {
+			fakeError(w, http.StatusTooManyRequests, "TooManyRequests")
+			return false
+		}
+		return true
+	}
+	f.mtx.Unlock()
+
+	start := time.Now()
+	report, err := client.DisableServiceAccounts(context.Background(),
+		[]string{a, b}, &BulkOptions{
+			RateLimit:  50,
+			MaxRetries: 2,
+			RetryDelay: time.Millisecond,
+		})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Three requests spaced at 20ms take at least 40ms.
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected requests to be rate limited; took %v", elapsed)
+	}
+	if len(report.Succeeded()) != 2 || f.serviceAccount(a).Enabled ||
+		f.count(http.MethodDelete, "/service-accounts") != 3 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestBulkNegativeMaxRetries(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	a := f.addServiceAccount(ServiceAcct{Name: "a"})
+
+	f.mtx.Lock()
+	f.before = func(w http.ResponseWriter, r *http.Request) bool {
+		fakeError(w, http.StatusTooManyRequests, "TooManyRequests")
+		return false
+	}
+	f.mtx.Unlock()
+
+	report, err := client.DisableServiceAccounts(context.Background(),
+		[]string{a}, &BulkOptions{MaxRetries: -1, RetryDelay: time.Millisecond})
+	if !errors.Is(err, ErrTooManyRequests) || report.Results[0].Err == nil ||
+		f.count(http.MethodDelete, "/service-accounts") != 1 {
+		t.Errorf("Expected a single attempt; got %v", err)
+	}
+}
+
+func TestBulkContextCancelled(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := client.GetServiceAccounts(ctx, []string{"a", "b"}, nil)
+	var bulkErr *BulkError
+	if !errors.As(err, &bulkErr) || bulkErr.Skipped != 2 ||
+		len(report.Succeeded()) != 0 {
+		t.Errorf("Expected all items to be skipped; got %v", err)
+	}
+	if n := f.count(http.MethodGet, "/service-accounts"); n != 0 {
+		t.Errorf("Expected no requests; got %d", n)
+	}
+}
@@ -0,0 +1,253 @@
+package lyveapi
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Canonical returns a copy of the document in canonical form, which makes
+// documents differing only in presentation identical once serialized:
+//
+//   - an empty Version is replaced with PolicyVersion
+//   - known action names use their documented spelling and the service prefix
+//     of other actions is lowercased
+//   - actions, resources, principals and condition values are deduplicated
+//     and sorted
+//   - identical statements are removed and the rest sorted
+//
+// Statement order has no effect on evaluation, so sorting statements does not
+// alter the meaning of the document.
+func (d *PolicyDocument) Canonical() *PolicyDocument {
+	out := &PolicyDocument{
+		Version: d.Version,
+		Id:      d.Id,
+	}
+
+	if out.Version == "" {
+		out.Version = PolicyVersion
+	}
+
+	keyed := make(map[string]Statement, len(d.Statement))
+	for i := range d.Statement {
+		s := d.Statement[i].canonical()
+		b, _ := json.Marshal(s)
+		keyed[string(b)] = s
+	}
+
+	for _, k := range sortedKeys(keyed) {
+		out.Statement = append(out.Statement, keyed[k])
+	}
+
+	return out
+}
+
+func (s *Statement) canonical() Statement {
+	return Statement{
+		Sid:          s.Sid,
+		Effect:       s.Effect,
+		Principal:    s.Principal.canonical(),
+		NotPrincipal: s.NotPrincipal.canonical(),
+		Action:       canonicalActions(s.Action),
+		NotAction:    canonicalActions(s.NotAction),
+		Resource:     canonicalList(s.Resource),
+		NotResource:  canonicalList(s.NotResource),
+		Condition:    s.Condition.canonical(),
+	}
+}
+
+func (p *Principal) canonical() *Principal {
+	if p == nil {
+		return nil
+	}
+	if p.Wildcard {
+		return WildcardPrincipal()
+	}
+
+	out := &Principal{Values: make(map[string]StringList, len(p.Values))}
+	for k, v := range p.Values {
+		out.Values[k] = canonicalList(v)
+	}
+	return out
+}
+
+func (c Condition) canonical() Condition {
+	if len(c) == 0 {
+		return nil
+	}
+
+	out := make(Condition, len(c))
+	for op, keys := range c {
+		out[op] = make(map[string]StringList, len(keys))
+		for k, v := range keys {
+			out[op][k] = canonicalList(v)
+		}
+	}
+	return out
+}
+
+// canonicalActions returns the actions with canonical spelling, deduplicated
+// and sorted.
+func canonicalActions(actions StringList) StringList {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	out := make(StringList, len(actions))
+	for i, a := range actions {
+		a = canonicalActionName(a)
+		if service, name, ok := strings.Cut(a, ":"); ok {
+			a = strings.ToLower(service) + ":" + name
+		}
+		out[i] = a
+	}
+	return canonicalList(out)
+}
+
+// canonicalList returns a sorted copy of the list without duplicates.
+func canonicalList(l StringList) StringList {
+	if len(l) == 0 {
+		return nil
+	}
+
+	out := append(StringList(nil), l...)
+	sort.Strings(out)
+
+	uniq := out[:1]
+	for _, v := range out[1:] {
+		if v != uniq[len(uniq)-1] {
+			uniq = append(uniq, v)
+		}
+	}
+	return uniq
+}
+
+// CanonicalPolicyJSON decodes a JSON policy document and returns its canonical
+// form serialized as compact JSON.
+func CanonicalPolicyJSON(policy string) (string, error) {
+	doc, err := ParsePolicyDocument(policy)
+	if err != nil {
+		return "", err
+	}
+	return doc.Canonical().JSON()
+}
+
+// PolicyDocumentsEqual reports whether two documents are semantically equal.
+// Besides presentation differences handled by Canonical, statement
+// identifiers, the document Id and the Version are ignored, and statements
+// are compared by the individual grants they make. This means a statement
+// allowing two actions is equal to two statements allowing one action each,
+// provided everything else about the statements is identical. Action names
+// and condition keys are compared case-insensitively.
+func PolicyDocumentsEqual(a, b *PolicyDocument) bool {
+	return setsEqual(a.grants(), b.grants())
+}
+
+// PolicyJSONEqual decodes two JSON policy documents and reports whether they
+// are semantically equal, as described by PolicyDocumentsEqual.
+func PolicyJSONEqual(a, b string) (bool, error) {
+	docA, err := ParsePolicyDocument(a)
+	if err != nil {
+		return false, err
+	}
+
+	docB, err := ParsePolicyDocument(b)
+	if err != nil {
+		return false, err
+	}
+
+	return PolicyDocumentsEqual(docA, docB), nil
+}
+
+// PermissionsEquivalent reports whether two permissions grant the same access
+// by comparing their equivalent policy documents, as returned by
+// ToPolicyDocument. A structured permission is therefore equivalent to a
+// policy permission carrying the same grants. Names, descriptions and other
+// metadata are not compared.
+func PermissionsEquivalent(a, b *Permission) (bool, error) {
+	docA, err := a.ToPolicyDocument()
+	if err != nil {
+		return false, err
+	}
+
+	docB, err := b.ToPolicyDocument()
+	if err != nil {
+		return false, err
+	}
+
+	return PolicyDocumentsEqual(docA, docB), nil
+}
+
+// grants expands the document into a set of strings, each describing a single
+// action and resource, along with the effect and any principal or conditions
+// of the statement it came from. NotAction and NotResource lists are kept
+// whole, since they cannot be split without changing their meaning.
+func (d *PolicyDocument) grants() map[string]bool {
+	set := map[string]bool{}
+
+	for i := range d.Statement {
+		s := d.Statement[i].canonical()
+
+		common := string(s.Effect) + "|" + principalKey(s.Principal) + "|" +
+			principalKey(s.NotPrincipal) + "|" + conditionKey(s.Condition)
+
+		var actions, resources []string
+		if len(s.NotAction) > 0 {
+			actions = []string{"!" + strings.ToLower(
+				strings.Join(canonicalList(lowerList(s.NotAction)), ","))}
+		} else {
+			actions = canonicalList(lowerList(s.Action))
+		}
+
+		if len(s.NotResource) > 0 {
+			resources = []string{"!" + strings.Join(s.NotResource, ",")}
+		} else {
+			resources = s.Resource
+		}
+
+		for _, a := range actions {
+			for _, r := range resources {
+				set[common+"|"+a+"|"+r] = true
+			}
+		}
+	}
+
+	return set
+}
+
+func lowerList(l StringList) StringList {
+	out := make(StringList, len(l))
+	for i, v := range l {
+		out[i] = strings.ToLower(v)
+	}
+	return out
+}
+
+func principalKey(p *Principal) string {
+	if p == nil {
+		return ""
+	}
+	b, _ := json.Marshal(p)
+	return string(b)
+}
+
+// conditionKey serializes conditions with lowercase condition keys. Maps are
+// serialized with sorted keys, which makes the result stable.
+func conditionKey(c Condition) string {
+	if len(c) == 0 {
+		return ""
+	}
+
+	lower := make(map[string]map[string][]string, len(c))
+	for op, keys := range c {
+		lower[op] = make(map[string][]string, len(keys))
+		for k, v := range keys {
+			lk := strings.ToLower(k)
+			lower[op][lk] = canonicalList(
+				append(StringList(lower[op][lk]), v...))
+		}
+	}
+
+	b, _ := json.Marshal(lower)
+	return string(b)
+}
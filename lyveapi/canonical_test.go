@@ -0,0 +1,125 @@
+package lyveapi
+
+import "testing"
+
+func TestCanonicalPolicyJSON(t *testing.T) {
+	t.Parallel()
+
+	a := `{"Statement":{"Effect":"Allow","Action":["S3:getobject","s3:ListBucket",
+		"s3:GetObject"],"Resource":"arn:aws:s3:::alpha/*"}}`
+	b := `{
+		"Version": "2012-10-17",
+		"Statement": [{
+			"Resource": ["arn:aws:s3:::alpha/*"],
+			"Action": ["s3:ListBucket", "s3:GetObject"],
+			"Effect": "Allow"
+		}]
+	}`
+
+	ca, err := CanonicalPolicyJSON(a)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cb, err := CanonicalPolicyJSON(b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if ca != cb {
+		t.Errorf("Expected identical canonical forms:\n%s\n%s", ca, cb)
+	}
+
+	expected := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow",` +
+		`"Action":["s3:GetObject","s3:ListBucket"],"Resource":"arn:aws:s3:::alpha/*"}]}`
+	if ca != expected {
+		t.Errorf("Unexpected canonical form: %s", ca)
+	}
+}
+
+func TestPolicyJSONEqual(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		a, b  string
+		equal bool
+	}{
+		{
+			name: "split-statements",
+			a: `{"Statement":[{"Sid":"one","Effect":"Allow","Action":["s3:GetObject",
+				"s3:PutObject"],"Resource":"arn:aws:s3:::alpha/*"}]}`,
+			b: `{"Statement":[
+				{"Effect":"Allow","Action":"s3:PutObject","Resource":"arn:aws:s3:::alpha/*"},
+				{"Effect":"Allow","Action":"s3:getobject","Resource":"arn:aws:s3:::alpha/*"}]}`,
+			equal: true,
+		},
+		{
+			name: "condition-key-case",
+			a: `{"Statement":[{"Effect":"Deny","Action":"s3:*","Resource":"*",
+				"Condition":{"NotIpAddress":{"aws:SourceIp":["10.0.0.0/8","192.168.0.0/16"]}}}]}`,
+			b: `{"Statement":[{"Effect":"Deny","Action":"s3:*","Resource":"*",
+				"Condition":{"NotIpAddress":{"aws:sourceip":["192.168.0.0/16","10.0.0.0/8"]}}}]}`,
+			equal: true,
+		},
+		{
+			name:  "different-effect",
+			a:     `{"Statement":[{"Effect":"Allow","Action":"s3:*","Resource":"*"}]}`,
+			b:     `{"Statement":[{"Effect":"Deny","Action":"s3:*","Resource":"*"}]}`,
+			equal: false,
+		},
+		{
+			name: "resource-case-sensitive",
+			a:    `{"Statement":[{"Effect":"Allow","Action":"s3:*","Resource":"arn:aws:s3:::a/X"}]}`,
+			b:    `{"Statement":[{"Effect":"Allow","Action":"s3:*","Resource":"arn:aws:s3:::a/x"}]}`,
+		},
+		{
+			name: "condition-not-split",
+			a: `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*",
+				"Condition":{"Bool":{"aws:SecureTransport":"true"}}}]}`,
+			b: `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"}]}`,
+		},
+	} {
+		t.Run(tc.name, func(tt *testing.T) {
+			equal, err := PolicyJSONEqual(tc.a, tc.b)
+			if err != nil {
+				tt.Fatalf("Unexpected error: %v", err)
+			}
+			if equal != tc.equal {
+				tt.Errorf("Expected equal=%v", tc.equal)
+			}
+		})
+	}
+}
+
+func TestPermissionsEquivalent(t *testing.T) {
+	t.Parallel()
+
+	structured := &Permission{
+		Name:    "uploads",
+		Type:    BucketNames,
+		Buckets: []string{"uploads", "archive"},
+		Actions: AllOperations,
+	}
+
+	policy := &Permission{
+		Name: "uploads-policy",
+		Type: Policy,
+		Policy: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:*",
+			"Resource":["arn:aws:s3:::archive","arn:aws:s3:::archive/*",
+			"arn:aws:s3:::uploads/*","arn:aws:s3:::uploads"]}]}`,
+	}
+
+	equal, err := PermissionsEquivalent(structured, policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !equal {
+		t.Error("Expected the permissions to be equivalent")
+	}
+
+	structured.Actions = ReadOnly
+	if equal, _ = PermissionsEquivalent(structured, policy); equal {
+		t.Error("Expected the permissions to differ")
+	}
+}
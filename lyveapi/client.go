@@ -2,7 +2,6 @@ package lyveapi
 
 import (
 	"context"
-	"encoding/json"
 	"io"
 	"net/http"
 	"strconv"
@@ -94,7 +93,8 @@ func newClientImpl(
 	now := time.Now()
 
 	if tokValidForSeconds, err = strconv.Atoi(auth.ExpirationSec); err != nil {
-		return nil, err
+		return nil, opError("Authenticate", http.MethodPost,
+			authEndpointUrl+"/auth/token", err)
 	}
 
 	// This is imprecise for a few reasons. First, we are rounding here, and
@@ -217,6 +217,8 @@ func (client *Client) TokenValidUntil() (time.Time, error) {
 }
 
 func getTokenExpiresDuration(token, apiUrl string) (time.Duration, error) {
+	const op = "GetTokenExpiration"
+
 	var err error
 	var expiresInSecs int
 	var rdr io.ReadCloser
@@ -229,21 +231,20 @@ func getTokenExpiresDuration(token, apiUrl string) (time.Duration, error) {
 	}
 
 	if rdr, err = apiRequestAuthenticated(
-		token, http.MethodGet, endpoint, nil); err != nil {
+		op, token, http.MethodGet, endpoint, nil); err != nil {
 		return 0, err
 	}
 
 	defer rdr.Close()
 
 	tok := Token{}
-	respBodyDecoder := json.NewDecoder(rdr)
-	if err = respBodyDecoder.Decode(&tok); err != nil {
+	if err = decodeResponseBody(rdr, &tok); err != nil {
 		return 0, err
 	}
 
 	if expiresInSecs, err =
 		strconv.Atoi(tok.ExpirationSec); err != nil {
-		return 0, err
+		return 0, opError(op, http.MethodGet, endpoint, err)
 	}
 
 	return time.Second * time.Duration(expiresInSecs), nil
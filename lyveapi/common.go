@@ -5,81 +5,375 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"html"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
+const (
+	// maxErrorBodyBytes limits how much of a failed response body we read.
+	maxErrorBodyBytes = 64 << 10
+	// maxErrorExcerptLen limits the length of the body excerpt reported in an
+	// UnexpectedResponseError.
+	maxErrorExcerptLen = 256
+)
+
+var (
+	htmlTitleRe     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlNonVisualRe = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	htmlTagRe       = regexp.MustCompile(`(?s)<[^>]*>`)
+	// Some responses contain escaped control characters, such as a literal
+	// '\n', instead of the characters themselves.
+	escapedCtrlRe = regexp.MustCompile(`\\[nrtv]`)
+)
+
+// UnexpectedResponseError is returned when the API, or a proxy in front of it,
+// responds with a failure which is not the JSON-serialized error object
+// described by the API contract, such as an HTML error page.
+type UnexpectedResponseError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// ContentType is the media type of the response body, if one was given.
+	ContentType string
+	// Title is the title of an HTML response body, if there was one.
+	Title string
+	// Excerpt is a bounded excerpt of the visible text in the response body.
+	Excerpt string
+	// Gateway is true when the response appears to come from a gateway or a
+	// proxy in front of the API rather than the API itself.
+	Gateway bool
+	// Err is set when reading the response body failed.
+	Err error
+}
+
+func (e *UnexpectedResponseError) Error() string {
+	var b strings.Builder
+	b.WriteString("unexpected response from the API")
+
+	var details []string
+	if e.StatusCode != 0 {
+		details = append(details, "status "+strconv.Itoa(e.StatusCode))
+	}
+	if e.ContentType != "" {
+		details = append(details, e.ContentType)
+	}
+	if len(details) > 0 {
+		b.WriteString(" (" + strings.Join(details, ", ") + ")")
+	}
+
+	switch {
+	case e.Err != nil:
+		b.WriteString(": failed to read response body: " + e.Err.Error())
+	case e.Title == "" && e.Excerpt == "":
+		b.WriteString(": empty response body")
+	case e.Title != "" && !strings.HasPrefix(e.Excerpt, e.Title):
+		b.WriteString(": " + e.Title)
+		if e.Excerpt != "" {
+			b.WriteString(": " + e.Excerpt)
+		}
+	default:
+		b.WriteString(": " + e.Excerpt)
+	}
+
+	return b.String()
+}
+
+func (e *UnexpectedResponseError) Unwrap() error {
+	return e.Err
+}
+
 // decodeFailedApiResponse takes a response object from the API and converts it
 // into a more user-friendly native representation. It returns an exported
 // error type, which has methods for accessing the status code from the API and
-// the message.
+// the message. Responses which do not carry a JSON-serialized error object
+// result in an *UnexpectedResponseError.
 func decodeFailedApiResponse(resp *http.Response) error {
-	body := &bytes.Buffer{}
-	tRdr := io.TeeReader(resp.Body, body)
-	decoder := json.NewDecoder(tRdr)
-	respPayload := &requestFailedResp{}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+
+	unexpected := &UnexpectedResponseError{
+		StatusCode:  resp.StatusCode,
+		ContentType: contentType,
+		Gateway:     gatewayStatusCode(resp.StatusCode),
+	}
+
+	var body []byte
+	var err error
+	if resp.Body != nil {
+		// Reading until EOF handles chunked responses, where the content
+		// length is not known upfront.
+		if body, err = io.ReadAll(
+			io.LimitReader(resp.Body, maxErrorBodyBytes)); err != nil {
+			unexpected.Err = err
+			return unexpected
+		}
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return unexpected
+	}
 
 	// If we can successfully decode the failure payload we encode the response
 	// from the API as a ApiCallFailedError and return that to the caller.
-	// Otherwise we have to try to deal with JSON error parsing and return
-	// whatever we can figure out to the call to enable troubleshooting.
-	if err := decoder.Decode(respPayload); err == nil {
-		err = &ApiCallFailedError{
-			apiResp:        respPayload,
-			httpStatusCode: resp.StatusCode,
+	if body[0] == '{' {
+		respPayload := &requestFailedResp{}
+		if err := json.Unmarshal(body, respPayload); err == nil &&
+			(respPayload.Code != "" || respPayload.Message != "") {
+			return &ApiCallFailedError{
+				apiResp:        respPayload,
+				httpStatusCode: resp.StatusCode,
+			}
+		}
+	}
+
+	// The API does not always adhere to the specified contract and at times
+	// responds with HTML instead of JSON-serialized data. Proxies in front of
+	// the API may respond with HTML or plain text as well.
+	text := string(body)
+	if contentType == "text/html" || body[0] == '<' {
+		if m := htmlTitleRe.FindStringSubmatch(text); m != nil {
+			unexpected.Title = collapseWhitespace(html.UnescapeString(m[1]))
 		}
+		text = htmlNonVisualRe.ReplaceAllString(text, " ")
+		text = htmlTagRe.ReplaceAllString(text, " ")
+		text = html.UnescapeString(text)
+	}
+
+	text = escapedCtrlRe.ReplaceAllString(text, " ")
+	unexpected.Excerpt = truncateExcerpt(collapseWhitespace(text))
+
+	if !unexpected.Gateway {
+		unexpected.Gateway = gatewayText(unexpected.Title) ||
+			gatewayText(unexpected.Excerpt)
+	}
+
+	return unexpected
+}
+
+// gatewayStatusCode returns true for status codes typically produced by a
+// gateway or proxy when the API is unreachable.
+func gatewayStatusCode(code int) bool {
+	return code == http.StatusBadGateway ||
+		code == http.StatusServiceUnavailable ||
+		code == http.StatusGatewayTimeout
+}
+
+func gatewayText(s string) bool {
+	s = strings.ToLower(s)
+	return strings.Contains(s, "bad gateway") ||
+		strings.Contains(s, "gateway timeout") ||
+		strings.Contains(s, "gateway time-out") ||
+		strings.Contains(s, "service unavailable")
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncateExcerpt(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxErrorExcerptLen {
+		return s
+	}
+	return string(runes[:maxErrorExcerptLen]) + "..."
+}
+
+// requestIdHeaders are headers which may carry an identifier assigned to the
+// request by the API or by a proxy in front of it. The first one found is
+// reported in an OperationError.
+var requestIdHeaders = []string{
+	"X-Request-Id",
+	"X-Amz-Request-Id",
+	"X-Amzn-Requestid",
+	"X-Correlation-Id",
+	"Request-Id",
+}
+
+// sensitiveQueryParams are query parameters whose values are removed from
+// URLs before they are reported in errors.
+var sensitiveQueryParams = []string{
+	"token",
+	"secret",
+	"key",
+	"signature",
+	"password",
+	"credential",
+}
+
+// OperationError is returned by Client methods and describes which call
+// failed. The underlying error, such as an *ApiCallFailedError, remains
+// accessible through errors.Is and errors.As.
+type OperationError struct {
+	// Op is the logical name of the operation, such as "CreatePermission".
+	Op string
+	// Method is the HTTP method of the request.
+	Method string
+	// URL is the request URL with any credentials removed.
+	URL string
+	// StatusCode is the HTTP status code of the response, or zero if the
+	// failure occurred before a response was received.
+	StatusCode int
+	// RequestId is the identifier assigned to the request by the API, if the
+	// response included one.
+	RequestId string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *OperationError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Op)
+	if e.Method != "" || e.URL != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Method)
+		if e.Method != "" && e.URL != "" {
+			b.WriteString(" ")
+		}
+		b.WriteString(e.URL)
+	}
+
+	switch {
+	case e.StatusCode != 0 && e.RequestId != "":
+		fmt.Fprintf(&b, " (status %d, request id %s)", e.StatusCode, e.RequestId)
+	case e.StatusCode != 0:
+		fmt.Fprintf(&b, " (status %d)", e.StatusCode)
+	case e.RequestId != "":
+		fmt.Fprintf(&b, " (request id %s)", e.RequestId)
+	}
+
+	if e.Err != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Err.Error())
+	}
+
+	return b.String()
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// apiCall carries details about a single request made to the API, used for
+// decorating any errors with context.
+type apiCall struct {
+	op         string
+	method     string
+	url        string
+	statusCode int
+	requestId  string
+}
+
+// wrap returns err decorated with details of the call. A nil error is
+// returned unchanged, as is an error which is already an *OperationError.
+func (c *apiCall) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var opErr *OperationError
+	if errors.As(err, &opErr) {
 		return err
 	}
 
-	// Dealing with an error in JSON parsing. This is due to the API not always
-	// adhering to the specified contract and responding with HTML instead of
-	// JSON-serialized data.
-	// We expect that this is HTML and starts with a '<head>' and has multiple
-	// lines. We want to eliminate the '\r\n' bits and present this garbage as a
-	// single line, mostly for debug-ability.
-	var errMsg = "unable to decode response body"
-	if bodySlc, err := io.ReadAll(body); err != nil {
-		return errors.Join(
-			errors.New("failed to read contents of the response body"), err)
-	} else {
-		if len(bodySlc) > 0 {
-			if bodySlc[0] == '<' {
-				var c int
-				var slash bool
-				b := make([]byte, len(bodySlc))
-				for _, v := range bodySlc {
-					// Special characters like newline and carriage return are
-					// detected in two passes due to the leading slash.
-					switch v {
-					case '\\':
-						slash = true
-						continue
-					case 'v', 'n':
-						if slash {
-							slash = false
-							continue
-						}
-					}
-
-					b[c] = v
-					c++
-				}
+	return &OperationError{
+		Op:         c.op,
+		Method:     c.method,
+		URL:        redactUrl(c.url),
+		StatusCode: c.statusCode,
+		RequestId:  c.requestId,
+		Err:        err,
+	}
+}
+
+// opError decorates an error which occurred in a Client method outside of any
+// exchange with the API, such as a failure to encode the payload.
+func opError(op, method, url string, err error) error {
+	call := &apiCall{op: op, method: method, url: url}
+	return call.wrap(err)
+}
+
+// responseBody is the body of a successful response, which remembers the
+// call that produced it, so decoding errors can be reported with context.
+type responseBody struct {
+	io.ReadCloser
+	call *apiCall
+}
+
+// decodeResponseBody decodes the JSON body returned by apiRequestAuthenticated
+// into v, wrapping any failure with details of the call.
+func decodeResponseBody(rdr io.ReadCloser, v interface{}) error {
+	err := json.NewDecoder(rdr).Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	if body, ok := rdr.(*responseBody); ok {
+		return body.call.wrap(err)
+	}
+
+	return err
+}
 
-				errMsg = string(b[:c])
+// redactUrl removes user information and the values of sensitive query
+// parameters from rawUrl, making it safe to include in error messages.
+func redactUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+
+	u.User = nil
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			lk := strings.ToLower(k)
+			for _, s := range sensitiveQueryParams {
+				if strings.Contains(lk, s) {
+					q.Set(k, "REDACTED")
+					break
+				}
 			}
 		}
+		u.RawQuery = q.Encode()
 	}
 
-	// At this point we may have some garbage, but let's return that anyway. :(
-	return ApiErrorDecodingErr(
-		errors.New("dubious response from the API: " + errMsg))
+	return u.String()
+}
+
+// responseRequestId returns the request identifier from response headers if
+// there is one.
+func responseRequestId(resp *http.Response) string {
+	for _, h := range requestIdHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			return v
+		}
+	}
+	return ""
 }
 
 // apiRequestAuthenticated packages up requests to the API without attempting
 // to authenticate first. A valid token is required to complete requests
-// successfully.
+// successfully. The op argument is the logical name of the operation which is
+// included in any returned error.
 func apiRequestAuthenticated(
-	token, method, url string, payload []byte) (io.ReadCloser, error) {
+	op, token, method, url string, payload []byte) (io.ReadCloser, error) {
+	return apiRequestAuthenticatedWithContext(
+		context.Background(), op, token, method, url, payload)
+}
+
+// apiRequestAuthenticatedWithContext is identical to apiRequestAuthenticated,
+// except that the request is bound to ctx.
+func apiRequestAuthenticatedWithContext(ctx context.Context,
+	op, token, method, url string, payload []byte) (io.ReadCloser, error) {
 	headers := map[string][]string{
 		"Accept": {
 			"application/json",
@@ -89,6 +383,8 @@ func apiRequestAuthenticated(
 		},
 	}
 
+	call := &apiCall{op: op, method: method, url: url}
+
 	var data *bytes.Buffer
 	var req *http.Request
 	var resp *http.Response
@@ -100,13 +396,13 @@ func apiRequestAuthenticated(
 			"application/json",
 		}
 		data = bytes.NewBuffer(payload)
-		req, err = http.NewRequest(method, url, data)
+		req, err = http.NewRequestWithContext(ctx, method, url, data)
 	} else {
-		req, err = http.NewRequest(method, url, nil)
+		req, err = http.NewRequestWithContext(ctx, method, url, nil)
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, call.wrap(err)
 	}
 
 	req.Header = headers
@@ -118,9 +414,12 @@ func apiRequestAuthenticated(
 	// We should not expect err != nil if the status code from the API is
 	// anything other than 200.
 	if err != nil {
-		return nil, err
+		return nil, call.wrap(err)
 	}
 
+	call.statusCode = resp.StatusCode
+	call.requestId = responseRequestId(resp)
+
 	// DEBUGGING:
 	// buf, _ := io.ReadAll(resp.Body)
 	// body := bytes.NewBuffer(buf)
@@ -133,10 +432,6 @@ func apiRequestAuthenticated(
 	// the caller.
 	// If the response is not http.StatusOK, look for an error response object.
 	if resp.StatusCode != http.StatusOK {
-		if resp.Body == nil {
-			return nil, errors.New("non-200 response did not come with any reason for failure")
-		}
-
 		// Re-enable bits below for additional debugging
 		// respBody := make([]byte, 4096)
 		// resp.Body.Read(respBody)
@@ -144,20 +439,17 @@ func apiRequestAuthenticated(
 		// log.Print("DEBUG: response body: ", string(respBody))
 		// We need to be sure to close the body, since we are not going to
 		// return it to the caller in this error path.
-		defer resp.Body.Close()
-
-		// If we encountered an issue decoding the body, return nil along with
-		// the error surfaced during decoding. Otherwise return nil along with
-		// decoded error as ApiCallFailedError.
-		if resp.ContentLength > 0 {
-			return nil, decodeFailedApiResponse(resp)
-		} else {
-			return nil, errors.New("Unexpected empty body in the response")
+		if resp.Body != nil {
+			defer resp.Body.Close()
 		}
+
+		// The decoded error is either an ApiCallFailedError, or when the body
+		// is empty or not the expected JSON object an UnexpectedResponseError.
+		return nil, call.wrap(decodeFailedApiResponse(resp))
 	}
 
 	// Handle http.StatusOK response next.
-	return resp.Body, nil
+	return &responseBody{resp.Body, call}, nil
 }
 
 // Authenticate attempts to authenticate against the API and returns a token
@@ -165,6 +457,8 @@ func apiRequestAuthenticated(
 // hours. This expiration period appears to be fixed but Lyve Cloud may change
 // it at any time.
 func Authenticate(ctx context.Context, credentials *Credentials, authEndpointUrl string) (*Token, error) {
+	const op = "Authenticate"
+
 	var data *bytes.Buffer
 
 	headers := map[string][]string{
@@ -176,37 +470,45 @@ func Authenticate(ctx context.Context, credentials *Credentials, authEndpointUrl
 		},
 	}
 
+	call := &apiCall{
+		op:     op,
+		method: http.MethodPost,
+		url:    authEndpointUrl + "/auth/token",
+	}
+
 	if buf, err := json.Marshal(credentials); err != nil {
-		return nil, err
+		return nil, call.wrap(err)
 	} else {
 		data = bytes.NewBuffer(buf)
 	}
 
-	req, err := http.NewRequestWithContext(
-		ctx, http.MethodPost, authEndpointUrl+"/auth/token", data)
-	req.Header = headers
-
+	req, err := http.NewRequestWithContext(ctx, call.method, call.url, data)
 	if err != nil {
-		return nil, err
+		return nil, call.wrap(err)
 	}
 
+	req.Header = headers
+
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, call.wrap(err)
 	}
 
 	defer resp.Body.Close()
 
+	call.statusCode = resp.StatusCode
+	call.requestId = responseRequestId(resp)
+
 	respBodyDecoder := json.NewDecoder(resp.Body)
 	authTok := &Token{}
 
 	if resp.StatusCode != 200 {
-		return nil, decodeFailedApiResponse(resp)
+		return nil, call.wrap(decodeFailedApiResponse(resp))
 	}
 
 	if err := respBodyDecoder.Decode(authTok); err != nil {
-		return nil, err
+		return nil, call.wrap(err)
 	}
 
 	return authTok, nil
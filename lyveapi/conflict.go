@@ -0,0 +1,193 @@
+package lyveapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// UpdateOption modifies the behaviour of UpdatePermission and
+// UpdateServiceAccount.
+type UpdateOption func(*updateOptions)
+
+type updateOptions struct {
+	// expected is the *Permission or *ServiceAcct given to IfUnchanged, or
+	// nil if it was not given. It holds a typed nil if IfUnchanged was given
+	// a nil pointer.
+	expected any
+}
+
+func newUpdateOptions(opts []UpdateOption) *updateOptions {
+	o := &updateOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// IfUnchanged makes UpdatePermission or UpdateServiceAccount write the update
+// only if the object on the server still matches expected, which is usually
+// the object as previously fetched with GetPermission or GetServiceAccount.
+// The object is fetched again immediately before writing, and if any of its
+// settings differ from expected an *UpdateConflictError, wrapped in an
+// *OperationError, is returned and nothing is written. expected must not be
+// nil. Fields maintained by the API, such as ReadyState, are
+// not compared.
+//
+// The API offers no conditional update, so a change made between the check
+// and the write is still overwritten, but the window is reduced to a single
+// round trip.
+func IfUnchanged[T Permission | ServiceAcct](expected *T) UpdateOption {
+	return func(o *updateOptions) {
+		o.expected = expected
+	}
+}
+
+// FieldChange describes a field of an object which differs from its expected
+// value.
+type FieldChange struct {
+	// Field is the JSON name of the field, such as "description".
+	Field string
+	// Expected is the value the caller expected the field to have.
+	Expected string
+	// Actual is the value found on the server.
+	Actual string
+}
+
+func (c FieldChange) String() string {
+	return c.Field + ": expected " + c.Expected + ", found " + c.Actual
+}
+
+// UpdateConflictError is returned by UpdatePermission and UpdateServiceAccount
+// with the IfUnchanged option when the object changed on the server since the
+// expected state was fetched. It lists the fields which changed.
+type UpdateConflictError struct {
+	// Kind is PermissionKind or ServiceAccountKind.
+	Kind string
+	// Id is the ID of the object.
+	Id string
+	// Changes lists the fields which differ from the expected state.
+	Changes []FieldChange
+}
+
+func (e *UpdateConflictError) Error() string {
+	changes := make([]string, len(e.Changes))
+	for i, c := range e.Changes {
+		changes[i] = c.String()
+	}
+	return e.Kind + " " + e.Id + " was modified concurrently: " +
+		strings.Join(changes, "; ")
+}
+
+// Is reports whether target is ErrConcurrentModification.
+func (e *UpdateConflictError) Is(target error) bool {
+	return target == ErrConcurrentModification
+}
+
+// fieldDiff accumulates the fields which differ between two objects.
+type fieldDiff []FieldChange
+
+func (d *fieldDiff) string(field, expected, actual string) {
+	if expected != actual {
+		*d = append(*d, FieldChange{
+			Field:    field,
+			Expected: strconv.Quote(expected),
+			Actual:   strconv.Quote(actual),
+		})
+	}
+}
+
+func (d *fieldDiff) set(field string, expected, actual []string) {
+	if !setsEqual(stringSet(expected), stringSet(actual)) {
+		*d = append(*d, FieldChange{
+			Field:    field,
+			Expected: "[" + strings.Join(expected, ", ") + "]",
+			Actual:   "[" + strings.Join(actual, ", ") + "]",
+		})
+	}
+}
+
+// diffPermissions returns the settings of actual which differ from expected.
+// Bucket names are compared without regard to order, and policies
+// semantically when both parse.
+func diffPermissions(expected, actual *Permission) []FieldChange {
+	var d fieldDiff
+	d.string("name", expected.Name, actual.Name)
+	d.string("description", expected.Description, actual.Description)
+	d.string("type", string(expected.Type), string(actual.Type))
+	d.string("actions", string(expected.Actions), string(actual.Actions))
+	d.string("prefix", expected.Prefix, actual.Prefix)
+	d.set("buckets", expected.Buckets, actual.Buckets)
+	if eq, err := PolicyJSONEqual(expected.Policy, actual.Policy); err != nil || !eq {
+		d.string("policy", expected.Policy, actual.Policy)
+	}
+	return d
+}
+
+// diffServiceAccounts returns the settings of actual which differ from
+// expected. Permission IDs are compared without regard to order.
+func diffServiceAccounts(expected, actual *ServiceAcct) []FieldChange {
+	var d fieldDiff
+	d.string("name", expected.Name, actual.Name)
+	d.string("description", expected.Description, actual.Description)
+	d.string("enabled", strconv.FormatBool(expected.Enabled),
+		strconv.FormatBool(actual.Enabled))
+	if !expected.ExpirationDate.Equal(actual.ExpirationDate) {
+		d.string("expirationDate", string(expected.ExpirationDate),
+			string(actual.ExpirationDate))
+	}
+	d.set("permissions", expected.Permissions, actual.Permissions)
+	return d
+}
+
+// checkPermissionUnchanged applies the IfUnchanged option of UpdatePermission.
+func (client *Client) checkPermissionUnchanged(
+	op, url, permissionId string, o *updateOptions) error {
+	if o.expected == nil {
+		return nil
+	}
+
+	expected, ok := o.expected.(*Permission)
+	if !ok || expected == nil {
+		return opError(op, http.MethodPut, url,
+			errors.New("IfUnchanged requires the expected permission"))
+	}
+
+	actual, err := client.GetPermission(permissionId)
+	if err != nil {
+		return err
+	}
+
+	if changes := diffPermissions(expected, actual); len(changes) > 0 {
+		return opError(op, http.MethodPut, url, &UpdateConflictError{
+			Kind: PermissionKind, Id: permissionId, Changes: changes})
+	}
+	return nil
+}
+
+// checkServiceAccountUnchanged applies the IfUnchanged option of
+// UpdateServiceAccount.
+func (client *Client) checkServiceAccountUnchanged(
+	op, url, svcAcctId string, o *updateOptions) error {
+	if o.expected == nil {
+		return nil
+	}
+
+	expected, ok := o.expected.(*ServiceAcct)
+	if !ok || expected == nil {
+		return opError(op, http.MethodPut, url,
+			errors.New("IfUnchanged requires the expected service account"))
+	}
+
+	actual, err := client.GetServiceAccount(svcAcctId)
+	if err != nil {
+		return err
+	}
+
+	if changes := diffServiceAccounts(expected, actual); len(changes) > 0 {
+		return opError(op, http.MethodPut, url, &UpdateConflictError{
+			Kind: ServiceAccountKind, Id: svcAcctId, Changes: changes})
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package lyveapi
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestUpdatePermissionIfUnchanged(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	id := f.addPermission(Permission{
+		Name: "p", Description: "old", Type: BucketNames,
+		Buckets: []string{"alpha", "beta"}, Actions: ReadOnly})
+
+	fetched, err := client.GetPermission(id)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Reordering buckets is not a change.
+	reordered := *fetched
+	reordered.Buckets = []string{"beta", "alpha"}
+	if err = client.UpdatePermission(id, &reordered); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	update := *fetched
+	update.Actions = AllOperations
+	if err = client.UpdatePermission(id, &update, IfUnchanged(fetched)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	concurrent := update
+	concurrent.Description = "new"
+	if err = client.UpdatePermission(id, &concurrent); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	puts := f.count(http.MethodPut, "/permissions")
+	stale := update
+	stale.Actions = WriteOnly
+	err = client.UpdatePermission(id, &stale, IfUnchanged(&update))
+
+	var conflict *UpdateConflictError
+	if !errors.As(err, &conflict) || !IsConflict(err) {
+		t.Fatalf("Expected a conflict; got %v", err)
+	}
+	expected := []FieldChange{
+		{Field: "description", Expected: `"old"`, Actual: `"new"`}}
+	if !reflect.DeepEqual(conflict.Changes, expected) {
+		t.Errorf("Unexpected changes: %+v", conflict.Changes)
+	}
+	if f.count(http.MethodPut, "/permissions") != puts ||
+		f.permission(id).Actions != AllOperations {
+		t.Error("Expected nothing to be written")
+	}
+
+	var opErr *OperationError
+	if !errors.As(err, &opErr) || opErr.Op != "UpdatePermission" {
+		t.Errorf("Expected an operation error; got %v", err)
+	}
+
+	for _, opt := range []UpdateOption{
+		IfUnchanged(&ServiceAcct{}), IfUnchanged((*Permission)(nil))} {
+		err = client.UpdatePermission(id, &stale, opt)
+		if err == nil || IsConflict(err) {
+			t.Errorf("Expected the option to be rejected; got %v", err)
+		}
+	}
+}
+
+func TestUpdateServiceAccountIfUnchanged(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p1 := f.addPermission(Permission{Name: "p1", Type: AllBuckets, Actions: ReadOnly})
+	p2 := f.addPermission(Permission{Name: "p2", Type: AllBuckets, Actions: ReadOnly})
+	id := f.addServiceAccount(ServiceAcct{
+		Name: "a", Enabled: true, Permissions: []string{p1}})
+
+	fetched, err := client.GetServiceAccount(id)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err = client.AttachPermissions(id, p2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	update := *fetched
+	update.Description = "new"
+	err = client.UpdateServiceAccount(id, &update, IfUnchanged(fetched))
+
+	var conflict *UpdateConflictError
+	if !errors.As(err, &conflict) || len(conflict.Changes) != 1 ||
+		conflict.Changes[0].Field != "permissions" ||
+		conflict.Kind != ServiceAccountKind {
+		t.Fatalf("Expected a conflict on permissions; got %v", err)
+	}
+	if f.serviceAccount(id).Description != "" {
+		t.Error("Expected nothing to be written")
+	}
+
+	err = client.UpdateServiceAccount(id, &update,
+		IfUnchanged((*ServiceAcct)(nil)))
+	if err == nil || IsConflict(err) {
+		t.Errorf("Expected a nil expected account to be rejected; got %v", err)
+	}
+}
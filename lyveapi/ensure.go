@@ -0,0 +1,163 @@
+package lyveapi
+
+import "errors"
+
+// EnsureAction describes what an Ensure operation did to reach the desired
+// state.
+type EnsureAction string
+
+const (
+	// EnsureCreated means the object did not exist and was created.
+	EnsureCreated EnsureAction = "created"
+	// EnsureUpdated means the object existed but differed from the desired
+	// state, and was updated.
+	EnsureUpdated EnsureAction = "updated"
+	// EnsureUnchanged means the object already matched the desired state.
+	EnsureUnchanged EnsureAction = "unchanged"
+)
+
+// EnsuredServiceAcct is the result of EnsureServiceAccount.
+type EnsuredServiceAcct struct {
+	// Action is what was done to reach the desired state.
+	Action EnsureAction
+	// Id is the ID of the service account.
+	Id string
+	// Credentials are the credentials of a newly created service account.
+	// The secret is only returned by the API when the account is created, so
+	// this is nil unless Action is EnsureCreated.
+	Credentials *CreateServiceAcctResp
+}
+
+// EnsurePermission makes sure a permission with the name and settings of
+// desired exists, which makes deployment scripts safe to repeat. The
+// permission is looked up by name and created if absent. An existing
+// permission is updated only if its description, type or type-specific
+// settings differ from desired, where policies are compared semantically with
+// PolicyJSONEqual and bucket names without regard to order.
+//
+// The resulting permission is returned along with the action taken. A
+// *NameLookupError is returned if more than one permission has the name.
+func (client *Client) EnsurePermission(
+	desired *Permission) (*Permission, EnsureAction, error) {
+	if err := desired.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	found, err := client.FindPermissionByName(desired.Name)
+	if errors.Is(err, ErrNameNotFound) {
+		created, err := client.CreatePermission(desired)
+		if err != nil {
+			return nil, "", err
+		}
+		return created, EnsureCreated, nil
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	existing, err := client.GetPermission(found.Id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if permissionSettingsEqual(existing, desired) {
+		return existing, EnsureUnchanged, nil
+	}
+
+	if err = client.UpdatePermission(existing.Id, desired); err != nil {
+		return nil, "", err
+	}
+
+	updated := *desired
+	updated.Id = existing.Id
+	updated.ReadyState = existing.ReadyState
+	updated.CreateTime = existing.CreateTime
+	return &updated, EnsureUpdated, nil
+}
+
+// EnsureServiceAccount makes sure a service account with the name,
+// description and permissions of desired exists, which makes deployment
+// scripts safe to repeat. The account is looked up by name and created if
+// absent. An existing account is updated only if its description or its set
+// of permissions differ from desired. Other settings of an existing account,
+// such as whether it is enabled, are left unchanged.
+//
+// A *NameLookupError is returned if more than one service account has the
+// name.
+func (client *Client) EnsureServiceAccount(
+	desired *CreateServiceAcctReq) (*EnsuredServiceAcct, error) {
+	if err := desired.Validate(); err != nil {
+		return nil, err
+	}
+
+	found, err := client.FindServiceAccountByName(desired.Name)
+	if errors.Is(err, ErrNameNotFound) {
+		resp, err := client.CreateServiceAccount(desired)
+		if err != nil {
+			return nil, err
+		}
+		return &EnsuredServiceAcct{
+			Action:      EnsureCreated,
+			Id:          resp.Id,
+			Credentials: resp,
+		}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Listings may omit the permissions of each account, so fetch the account
+	// to compare them.
+	existing, err := client.GetServiceAccount(found.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EnsuredServiceAcct{Action: EnsureUnchanged, Id: existing.Id}
+	if existing.Description == desired.Description &&
+		setsEqual(stringSet(existing.Permissions),
+			stringSet(desired.Permissions)) {
+		return result, nil
+	}
+
+	// Only the compared settings are written, so concurrent changes to the
+	// others are kept.
+	if _, err = client.UpdateServiceAccountFields(existing.Id,
+		&ServiceAcctUpdateReq{
+			Description: &desired.Description,
+			Permissions: &desired.Permissions,
+		}); err != nil {
+		return nil, err
+	}
+
+	result.Action = EnsureUpdated
+	return result, nil
+}
+
+// permissionSettingsEqual returns true when the description, type and
+// type-specific settings of two permissions are equal.
+func permissionSettingsEqual(a, b *Permission) bool {
+	if a.Description != b.Description || a.Type != b.Type {
+		return false
+	}
+
+	switch a.Type {
+	case AllBuckets:
+		return a.Actions == b.Actions
+	case BucketPrefix:
+		return a.Actions == b.Actions && a.Prefix == b.Prefix
+	case BucketNames:
+		return a.Actions == b.Actions &&
+			setsEqual(stringSet(a.Buckets), stringSet(b.Buckets))
+	case Policy:
+		equal, err := PolicyJSONEqual(a.Policy, b.Policy)
+		return err == nil && equal
+	}
+	return false
+}
+
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,137 @@
+package lyveapi
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEnsurePermission(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+
+	desired := &Permission{
+		Name:    "uploads",
+		Type:    BucketNames,
+		Buckets: []string{"alpha", "beta"},
+		Actions: WriteOnly,
+	}
+
+	p, action, err := client.EnsurePermission(desired)
+	if err != nil || action != EnsureCreated || p.Id == "" {
+		t.Fatalf("Unexpected result: %+v, %s, %v", p, action, err)
+	}
+
+	// Bucket order does not matter.
+	again := *desired
+	again.Buckets = []string{"beta", "alpha"}
+	if _, action, err = client.EnsurePermission(&again); err != nil ||
+		action != EnsureUnchanged {
+		t.Errorf("Expected no change; got %s, %v", action, err)
+	}
+	if n := f.count(http.MethodPut, "/permissions"); n != 0 {
+		t.Errorf("Expected no updates; got %d", n)
+	}
+
+	again.Actions = AllOperations
+	updated, action, err := client.EnsurePermission(&again)
+	if err != nil || action != EnsureUpdated || updated.Id != p.Id {
+		t.Fatalf("Unexpected result: %+v, %s, %v", updated, action, err)
+	}
+	if f.permission(p.Id).Actions != AllOperations {
+		t.Errorf("Expected the permission to be updated: %+v",
+			f.permission(p.Id))
+	}
+}
+
+func TestEnsurePermissionPolicy(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	id := f.addPermission(Permission{
+		Name: "reports",
+		Type: Policy,
+		Policy: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow",
+			"Action":["s3:ListBucket","s3:GetObject"],
+			"Resource":["arn:aws:s3:::reports","arn:aws:s3:::reports/*"]}]}`,
+	})
+
+	desired := &Permission{
+		Name: "reports",
+		Type: Policy,
+		Policy: `{"Statement":[
+			{"Effect":"Allow","Action":"s3:GetObject","Resource":["arn:aws:s3:::reports/*","arn:aws:s3:::reports"]},
+			{"Effect":"Allow","Action":"s3:ListBucket","Resource":["arn:aws:s3:::reports/*","arn:aws:s3:::reports"]}]}`,
+	}
+
+	if _, action, err := client.EnsurePermission(desired); err != nil ||
+		action != EnsureUnchanged {
+		t.Errorf("Expected an equivalent policy to be unchanged; got %s, %v",
+			action, err)
+	}
+	if n := f.count(http.MethodPut, "/permissions/"); n != 0 ||
+		!strings.HasPrefix(f.permission(id).Policy, `{"Version"`) {
+		t.Errorf("Expected the stored policy to be kept; got %d updates", n)
+	}
+}
+
+func TestEnsureServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p1 := f.addPermission(Permission{Name: "p1", Type: AllBuckets, Actions: ReadOnly})
+	p2 := f.addPermission(Permission{Name: "p2", Type: AllBuckets, Actions: WriteOnly})
+
+	desired := &CreateServiceAcctReq{
+		Name:        "ingest",
+		Description: "ingest pipeline",
+		Permissions: []string{p1},
+	}
+
+	res, err := client.EnsureServiceAccount(desired)
+	if err != nil || res.Action != EnsureCreated || res.Credentials == nil ||
+		res.Credentials.Secret == "" {
+		t.Fatalf("Unexpected result: %+v, %v", res, err)
+	}
+
+	if err = client.DisableServiceAccount(res.Id); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if res, err = client.EnsureServiceAccount(desired); err != nil ||
+		res.Action != EnsureUnchanged || res.Credentials != nil {
+		t.Errorf("Expected no change; got %+v, %v", res, err)
+	}
+
+	var body string
+	f.mtx.Lock()
+	f.before = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodPut {
+			b, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(b))
+			body = string(b)
+		}
+		return true
+	}
+	f.mtx.Unlock()
+
+	desired.Permissions = []string{p2, p1}
+	if res, err = client.EnsureServiceAccount(desired); err != nil ||
+		res.Action != EnsureUpdated {
+		t.Fatalf("Expected an update; got %+v, %v", res, err)
+	}
+	expected := `{"description":"ingest pipeline","permissions":["` + p2 +
+		`","` + p1 + `"]}`
+	if body != expected {
+		t.Errorf("Expected only the compared settings to be sent: %s", body)
+	}
+
+	a := f.serviceAccount(res.Id)
+	if len(a.Permissions) != 2 || a.Enabled {
+		t.Errorf("Expected permissions to change and the account to stay "+
+			"disabled: %+v", a)
+	}
+}
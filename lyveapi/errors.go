@@ -3,36 +3,171 @@ package lyveapi
 import (
 	"encoding/json"
 	"errors"
+	"net/http"
+	"strings"
 )
 
 var (
-	InvalidPermissionsErrMsg   = "permission IDs are not known to the API; fetch a list of permissions to obtain correct IDs"
-	InvalidTokenErrMsg         = "token presented to the API is invalid"
-	ExpiredTokenErrMsg         = "token presented to the API is already expired"
-	AuthenticationFailedErrMsg = "authentication was unsuccessful; check supplied credentials"
-	PermissionExistsErrMsg     = "permission name is already taken"
-	PolicyMissingErrMsg        = "permission is missing required policy JSON document"
-	PermissionNoExistErrMsg    = "permission does not exist"
+	InvalidPermissionsErrMsg    = "permission IDs are not known to the API; fetch a list of permissions to obtain correct IDs"
+	InvalidTokenErrMsg          = "token presented to the API is invalid"
+	ExpiredTokenErrMsg          = "token presented to the API is already expired"
+	AuthenticationFailedErrMsg  = "authentication was unsuccessful; check supplied credentials"
+	PermissionExistsErrMsg      = "permission name is already taken"
+	PolicyMissingErrMsg         = "permission is missing required policy JSON document"
+	PermissionNoExistErrMsg     = "permission does not exist"
+	ServiceAcctExistsErrMsg     = "service account name is already taken"
+	ServiceAcctNoExistErrMsg    = "service account does not exist"
+	InvalidArgumentErrMsg       = "request contains an invalid argument"
+	InvalidTimeRangeErrMsg      = "requested time range is invalid or exceeds six months"
+	InternalErrorErrMsg         = "the API encountered an internal error"
+	ServiceUnavailableErrMsg    = "the API is temporarily unavailable"
+	TooManyRequestsErrMsg       = "request rate limit exceeded"
+	AccessDeniedErrMsg          = "access to the requested resource was denied"
+	BucketNoExistErrMsg         = "bucket does not exist"
+	NoPermissionsProvidedErrMsg = "at least one permission ID is required"
+	ServiceAcctExpiredErrMsg    = "service account has expired"
 
 	// These errors are not something we get back from the API and convert
 	// into an ApiCallFailedError. These are internal, indicating improper
 	// usage or some other fault condition.
-	PolicyMissingErr = errors.New(PermissionExistsErrMsg)
+	PolicyMissingErr = errors.New(PolicyMissingErrMsg)
 )
 
+// Sentinel errors matching failure codes returned by the API. An
+// *ApiCallFailedError reports true from errors.Is for the sentinel matching its
+// code, which means consumers can write errors.Is(err, ErrPermissionNotFound)
+// instead of comparing code strings.
+var (
+	ErrExpiredToken          = errors.New(ExpiredTokenErrMsg)
+	ErrInvalidToken          = errors.New(InvalidTokenErrMsg)
+	ErrAuthenticationFailed  = errors.New(AuthenticationFailedErrMsg)
+	ErrPermissionNotFound    = errors.New(PermissionNoExistErrMsg)
+	ErrPermissionExists      = errors.New(PermissionExistsErrMsg)
+	ErrInvalidPermissions    = errors.New(InvalidPermissionsErrMsg)
+	ErrServiceAcctNotFound   = errors.New(ServiceAcctNoExistErrMsg)
+	ErrServiceAcctExists     = errors.New(ServiceAcctExistsErrMsg)
+	ErrServiceAcctExpired    = errors.New(ServiceAcctExpiredErrMsg)
+	ErrNoPermissionsProvided = errors.New(NoPermissionsProvidedErrMsg)
+	ErrInvalidArgument       = errors.New(InvalidArgumentErrMsg)
+	ErrInvalidTimeRange      = errors.New(InvalidTimeRangeErrMsg)
+	ErrBucketNotFound        = errors.New(BucketNoExistErrMsg)
+	ErrAccessDenied          = errors.New(AccessDeniedErrMsg)
+	ErrInternal              = errors.New(InternalErrorErrMsg)
+	ErrServiceUnavailable    = errors.New(ServiceUnavailableErrMsg)
+	ErrTooManyRequests       = errors.New(TooManyRequestsErrMsg)
+)
+
+// ApiErrorDecodingErr was previously returned when a failure response could
+// not be decoded.
+//
+// Deprecated: such failures are now reported as *UnexpectedResponseError.
 type ApiErrorDecodingErr error
 
+// apiErrorCode describes a failure code the API is known to return.
+type apiErrorCode struct {
+	sentinel    error
+	remediation string
+	notFound    bool
+	conflict    bool
+	retryable   bool
+}
+
+var errorCodes = map[string]apiErrorCode{
+	"ExpiredToken": {
+		sentinel:    ErrExpiredToken,
+		remediation: "obtain a new token by creating a new client with NewClient",
+	},
+	"InvalidToken": {
+		sentinel:    ErrInvalidToken,
+		remediation: "check that the token was issued by this API endpoint or re-authenticate with NewClient",
+	},
+	"AuthenticationFailed": {
+		sentinel:    ErrAuthenticationFailed,
+		remediation: "verify the account ID, access key and secret used to build Credentials",
+	},
+	"InvalidPermissions": {
+		sentinel:    ErrInvalidPermissions,
+		remediation: "list permissions with ListPermissions and use the returned IDs",
+	},
+	"PermissionNameAlreadyExists": {
+		sentinel:    ErrPermissionExists,
+		remediation: "choose a different name or update the existing permission",
+		conflict:    true,
+	},
+	"PermissionNotFound": {
+		sentinel:    ErrPermissionNotFound,
+		remediation: "list permissions with ListPermissions to confirm the permission ID",
+		notFound:    true,
+	},
+	"ServiceAccountNameAlreadyExists": {
+		sentinel:    ErrServiceAcctExists,
+		remediation: "choose a different name or update the existing service account",
+		conflict:    true,
+	},
+	"ServiceAccountNotFound": {
+		sentinel:    ErrServiceAcctNotFound,
+		remediation: "list service accounts with ListServiceAccounts to confirm the account ID",
+		notFound:    true,
+	},
+	"ServiceAccountExpired": {
+		sentinel:    ErrServiceAcctExpired,
+		remediation: "create a replacement service account; expired accounts cannot be renewed",
+	},
+	"NoPermissionsProvided": {
+		sentinel:    ErrNoPermissionsProvided,
+		remediation: "supply at least one permission ID when creating or updating a service account",
+	},
+	"InvalidArgument": {
+		sentinel:    ErrInvalidArgument,
+		remediation: "check the request fields against the API documentation",
+	},
+	"InvalidTimeRange": {
+		sentinel:    ErrInvalidTimeRange,
+		remediation: "limit the usage query to a range of at most six months",
+	},
+	"BucketNotFound": {
+		sentinel:    ErrBucketNotFound,
+		remediation: "check the bucket name; buckets are not managed through this API",
+		notFound:    true,
+	},
+	"AccessDenied": {
+		sentinel:    ErrAccessDenied,
+		remediation: "use credentials of an account which is allowed to perform this operation",
+	},
+	"InternalError": {
+		sentinel:    ErrInternal,
+		remediation: "retry the request after a short delay",
+		retryable:   true,
+	},
+	"ServiceUnavailable": {
+		sentinel:    ErrServiceUnavailable,
+		remediation: "retry the request after a short delay",
+		retryable:   true,
+	},
+	"TooManyRequests": {
+		sentinel:    ErrTooManyRequests,
+		remediation: "reduce the request rate and retry after a delay",
+		retryable:   true,
+	},
+}
+
+// errorCodesToErrors maps codes to the messages we present to the consumer
+// instead of the message returned by the API.
 var errorCodesToErrors = map[string]string{
-	"ExpiredToken":         ExpiredTokenErrMsg,
-	"InvalidToken":         InvalidTokenErrMsg,
-	"InvalidPermissions":   InvalidPermissionsErrMsg,
-	"AuthenticationFailed": AuthenticationFailedErrMsg,
-	// We are seemingly getting a trailing space in auth failure responses.
-	"AuthenticationFailed ":       AuthenticationFailedErrMsg,
+	"ExpiredToken":                ExpiredTokenErrMsg,
+	"InvalidToken":                InvalidTokenErrMsg,
+	"InvalidPermissions":          InvalidPermissionsErrMsg,
+	"AuthenticationFailed":        AuthenticationFailedErrMsg,
 	"PermissionNameAlreadyExists": PermissionExistsErrMsg,
 	"PermissionNotFound":          PermissionNoExistErrMsg,
 }
 
+// normalizeErrorCode cleans-up codes returned by the API. We are seemingly
+// getting a trailing space in auth failure responses.
+func normalizeErrorCode(code string) string {
+	return strings.TrimSpace(code)
+}
+
 type requestFailedResp struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
@@ -57,7 +192,7 @@ func (e *ApiCallFailedError) HttpStatusCode() int {
 
 func (e *ApiCallFailedError) Error() string {
 	var code, message string
-	if v, ok := errorCodesToErrors[e.apiResp.Code]; ok {
+	if v, ok := errorCodesToErrors[normalizeErrorCode(e.apiResp.Code)]; ok {
 		message = v
 	} else if e.apiResp.Message != "" {
 		message = e.apiResp.Message
@@ -78,3 +213,140 @@ func (e *ApiCallFailedError) JSON() []byte {
 	b, _ := json.Marshal(e.apiResp)
 	return b
 }
+
+// Is reports whether target is the sentinel error corresponding to the code
+// returned by the API, making this error usable with errors.Is.
+func (e *ApiCallFailedError) Is(target error) bool {
+	if e.apiResp == nil {
+		return false
+	}
+
+	if c, ok := errorCodes[normalizeErrorCode(e.apiResp.Code)]; ok {
+		return c.sentinel == target
+	}
+
+	return false
+}
+
+// Remediation returns a short hint describing what the consumer may do to
+// resolve the failure, or an empty string if we have nothing to suggest.
+func (e *ApiCallFailedError) Remediation() string {
+	if e.apiResp != nil {
+		if c, ok := errorCodes[normalizeErrorCode(e.apiResp.Code)]; ok {
+			return c.remediation
+		}
+	}
+
+	switch {
+	case e.httpStatusCode == http.StatusUnauthorized:
+		return "re-authenticate with NewClient and retry the request"
+	case e.httpStatusCode == http.StatusTooManyRequests:
+		return "reduce the request rate and retry after a delay"
+	case e.httpStatusCode >= http.StatusInternalServerError:
+		return "retry the request after a short delay"
+	}
+
+	return ""
+}
+
+// lookupErrorCode returns details about the code carried by err, if err is or
+// wraps an *ApiCallFailedError.
+func lookupErrorCode(err error) (*ApiCallFailedError, apiErrorCode, bool) {
+	var apiErr *ApiCallFailedError
+	if !errors.As(err, &apiErr) || apiErr.apiResp == nil {
+		return apiErr, apiErrorCode{}, false
+	}
+
+	c, ok := errorCodes[normalizeErrorCode(apiErr.apiResp.Code)]
+	return apiErr, c, ok
+}
+
+// IsNotFound returns true when err indicates that the requested permission,
+// service account or other object does not exist, including when a lookup by
+// name finds no object.
+func IsNotFound(err error) bool {
+	if errors.Is(err, ErrNameNotFound) {
+		return true
+	}
+
+	var unexpected *UnexpectedResponseError
+	if errors.As(err, &unexpected) {
+		return unexpected.StatusCode == http.StatusNotFound
+	}
+
+	apiErr, c, ok := lookupErrorCode(err)
+	if ok {
+		return c.notFound
+	}
+
+	return apiErr != nil && apiErr.httpStatusCode == http.StatusNotFound
+}
+
+// IsConflict returns true when err indicates that the request conflicts with
+// existing state, such as a name which is already taken, or that an object
+// was modified concurrently.
+func IsConflict(err error) bool {
+	if errors.Is(err, ErrConcurrentModification) {
+		return true
+	}
+
+	apiErr, c, ok := lookupErrorCode(err)
+	if ok {
+		return c.conflict
+	}
+
+	return apiErr != nil && apiErr.httpStatusCode == http.StatusConflict
+}
+
+// IsRetryable returns true when err indicates a transient failure, where the
+// same request may succeed if repeated after a delay.
+func IsRetryable(err error) bool {
+	var unexpected *UnexpectedResponseError
+	if errors.As(err, &unexpected) {
+		return unexpected.Gateway ||
+			retryableStatusCode(unexpected.StatusCode)
+	}
+
+	apiErr, c, ok := lookupErrorCode(err)
+	if ok {
+		return c.retryable
+	}
+
+	if apiErr == nil {
+		return false
+	}
+
+	return retryableStatusCode(apiErr.httpStatusCode)
+}
+
+// IsAuthError returns true when err indicates that the token or credentials
+// were rejected by the API.
+func IsAuthError(err error) bool {
+	return errors.Is(err, ErrExpiredToken) ||
+		errors.Is(err, ErrInvalidToken) ||
+		errors.Is(err, ErrAuthenticationFailed)
+}
+
+// Remediation returns a remediation hint for err if err is or wraps an
+// *ApiCallFailedError, otherwise an empty string is returned.
+func Remediation(err error) string {
+	var apiErr *ApiCallFailedError
+	if errors.As(err, &apiErr) {
+		return apiErr.Remediation()
+	}
+
+	return ""
+}
+
+func retryableStatusCode(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
@@ -1,6 +1,10 @@
 package lyveapi
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
 
 func TestApiCallFailedErrorMsg(t *testing.T) {
 	t.Parallel()
@@ -44,3 +48,66 @@ func TestApiCallFailedErrorMsg(t *testing.T) {
 		t.Errorf("actual: '%s' != expected: '%s'", e3.Error(), expect3)
 	}
 }
+
+func TestApiCallFailedErrorIs(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		code      string
+		status    int
+		sentinel  error
+		notFound  bool
+		conflict  bool
+		retryable bool
+	}
+
+	for _, tc := range []testCase{
+		{code: "PermissionNotFound", status: 404, sentinel: ErrPermissionNotFound, notFound: true},
+		{code: "PermissionNameAlreadyExists", status: 409, sentinel: ErrPermissionExists, conflict: true},
+		{code: "AuthenticationFailed ", status: 403, sentinel: ErrAuthenticationFailed},
+		{code: "ExpiredToken", status: 401, sentinel: ErrExpiredToken},
+		{code: "InternalError", status: 500, sentinel: ErrInternal, retryable: true},
+		{code: "SomethingNew", status: 503, retryable: true},
+		{code: "SomethingElse", status: 404, notFound: true},
+	} {
+		err := fmt.Errorf("wrapped: %w", &ApiCallFailedError{
+			apiResp:        &requestFailedResp{Code: tc.code},
+			httpStatusCode: tc.status,
+		})
+
+		if tc.sentinel != nil && !errors.Is(err, tc.sentinel) {
+			t.Errorf("%s: expected errors.Is to match %v", tc.code, tc.sentinel)
+		}
+
+		if errors.Is(err, ErrInvalidPermissions) {
+			t.Errorf("%s: unexpected match of ErrInvalidPermissions", tc.code)
+		}
+
+		if IsNotFound(err) != tc.notFound {
+			t.Errorf("%s: IsNotFound() != %v", tc.code, tc.notFound)
+		}
+
+		if IsConflict(err) != tc.conflict {
+			t.Errorf("%s: IsConflict() != %v", tc.code, tc.conflict)
+		}
+
+		if IsRetryable(err) != tc.retryable {
+			t.Errorf("%s: IsRetryable() != %v", tc.code, tc.retryable)
+		}
+
+		if tc.sentinel != nil && Remediation(err) == "" {
+			t.Errorf("%s: expected a remediation hint", tc.code)
+		}
+	}
+
+	if IsNotFound(errors.New("not an API error")) {
+		t.Error("IsNotFound() should be false for unrelated errors")
+	}
+
+	// A 404 page returned by a gateway rather than the API.
+	err := fmt.Errorf("wrapped: %w", &UnexpectedResponseError{
+		StatusCode: 404, ContentType: "text/html"})
+	if !IsNotFound(err) || IsRetryable(err) {
+		t.Error("Expected a non-JSON 404 to be classed as not found")
+	}
+}
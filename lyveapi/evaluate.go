@@ -0,0 +1,511 @@
+package lyveapi
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccessRequest describes an S3 request to be evaluated against a set of
+// permissions, for example whether an account may perform "s3:PutObject" on
+// key "reports/2023.csv" in bucket "alpha".
+type AccessRequest struct {
+	// Action is the S3 action, such as "s3:GetObject".
+	Action string
+	// Bucket is the name of the bucket, or empty for account-level actions
+	// such as "s3:ListAllMyBuckets".
+	Bucket string
+	// Key is the object key, or empty for bucket-level actions such as
+	// "s3:ListBucket".
+	Key string
+	// Context supplies values for condition keys and policy variables, such
+	// as "aws:SourceIp" or "aws:username". Keys are case-insensitive.
+	Context map[string][]string
+}
+
+// Resource returns the ARN of the bucket or object the request applies to.
+func (r *AccessRequest) Resource() string {
+	switch {
+	case r.Bucket == "":
+		return "*"
+	case r.Key == "":
+		return BucketARN(r.Bucket)
+	}
+	return ObjectARN(r.Bucket, r.Key)
+}
+
+// Decision is the outcome of evaluating an AccessRequest.
+type Decision struct {
+	// Allowed is true when the request is allowed.
+	Allowed bool
+	// Effect is the effect of the deciding statement. It is empty when no
+	// statement applied to the request, which results in an implicit deny.
+	Effect Effect
+	// PermissionId and PermissionName identify the permission containing
+	// the deciding statement.
+	PermissionId   string
+	PermissionName string
+	// Statement is the index of the deciding statement in the policy document
+	// of the permission, or -1 when no statement applied.
+	Statement int
+	// Sid is the identifier of the deciding statement, if it has one.
+	Sid string
+	// Reason describes the decision.
+	Reason string
+}
+
+// Evaluate decides whether the access request is allowed by the given
+// permissions, without contacting the API. Structured permissions, such as
+// those of type "bucket-prefix", are evaluated as their equivalent policy
+// documents. An explicit Deny in any permission overrides any Allow, and a
+// request to which no statement applies is implicitly denied.
+//
+// Principal and NotPrincipal elements are ignored, since permissions always
+// apply to the service accounts they are attached to. An error is returned
+// when a permission cannot be converted to a policy document or a condition
+// cannot be evaluated.
+func Evaluate(perms []Permission, req *AccessRequest) (*Decision, error) {
+	ctx := newEvalContext(req.Context)
+	resource := req.Resource()
+
+	var allow *Decision
+	for i := range perms {
+		p := &perms[i]
+		doc, err := permissionPolicyDocument(p)
+		if err != nil {
+			return nil, fmt.Errorf("permission %s: %w", permissionLabel(p), err)
+		}
+
+		for j := range doc.Statement {
+			s := &doc.Statement[j]
+			applies, err := s.appliesTo(req.Action, resource, ctx)
+			if err != nil {
+				return nil, fmt.Errorf("permission %s statement %d: %w",
+					permissionLabel(p), j, err)
+			}
+			if !applies {
+				continue
+			}
+
+			d := &Decision{
+				Allowed:        s.Effect == EffectAllow,
+				Effect:         s.Effect,
+				PermissionId:   p.Id,
+				PermissionName: p.Name,
+				Statement:      j,
+				Sid:            s.Sid,
+			}
+
+			if s.Effect == EffectDeny {
+				d.Reason = fmt.Sprintf("explicitly denied by statement %d "+
+					"of permission %s", j, permissionLabel(p))
+				return d, nil
+			}
+
+			if allow == nil {
+				d.Reason = fmt.Sprintf("allowed by statement %d of "+
+					"permission %s", j, permissionLabel(p))
+				allow = d
+			}
+		}
+	}
+
+	if allow != nil {
+		return allow, nil
+	}
+
+	return &Decision{
+		Statement: -1,
+		Reason:    "implicitly denied; no statement allows the request",
+	}, nil
+}
+
+func permissionLabel(p *Permission) string {
+	switch {
+	case p.Name != "":
+		return strconv.Quote(p.Name)
+	case p.Id != "":
+		return p.Id
+	}
+	return "(unnamed)"
+}
+
+// structuredActions maps the actions of structured permissions to the S3
+// actions they are understood to grant.
+var structuredActions = map[Action][]string{
+	ReadOnly:      {"s3:Get*", "s3:List*"},
+	WriteOnly:     {"s3:PutObject*", "s3:DeleteObject*", "s3:AbortMultipartUpload"},
+	AllOperations: {"s3:*"},
+}
+
+// permissionPolicyDocument returns the policy document of a policy permission,
+// or the equivalent policy document of a structured permission.
+func permissionPolicyDocument(p *Permission) (*PolicyDocument, error) {
+	if p.IsPolicyPermission() {
+		return p.PolicyDocument()
+	}
+
+	actions, ok := structuredActions[p.Actions]
+	if !ok {
+		return nil, fmt.Errorf("unknown action %q", p.Actions)
+	}
+
+	var resources []string
+	switch p.Type {
+	case AllBuckets:
+		// Unlike BucketARN("*"), "*" also covers account-level actions such
+		// as s3:ListAllMyBuckets, which apply to no bucket.
+		resources = []string{"*"}
+	case BucketPrefix:
+		if p.Prefix == "" {
+			return nil, fmt.Errorf("%s permission has no prefix", p.Type)
+		}
+		resources = []string{BucketARN(p.Prefix + "*")}
+	case BucketNames:
+		if len(p.Buckets) == 0 {
+			return nil, fmt.Errorf("%s permission has no buckets", p.Type)
+		}
+		for _, b := range p.Buckets {
+			resources = append(resources, BucketARN(b), ObjectARN(b, "*"))
+		}
+	default:
+		return nil, fmt.Errorf("unknown permission type %q", p.Type)
+	}
+
+	return &PolicyDocument{
+		Version: PolicyVersion,
+		Statement: []Statement{{
+			Effect:   EffectAllow,
+			Action:   append(StringList(nil), actions...),
+			Resource: resources,
+		}},
+	}, nil
+}
+
+// evalContext holds request context values keyed by lowercase condition key.
+type evalContext map[string][]string
+
+func newEvalContext(values map[string][]string) evalContext {
+	ctx := make(evalContext, len(values))
+	for k, v := range values {
+		ctx[strings.ToLower(k)] = v
+	}
+	return ctx
+}
+
+// lookup returns the values of a condition key and whether it is present.
+func (ctx evalContext) lookup(key string) ([]string, bool) {
+	v, ok := ctx[strings.ToLower(key)]
+	return v, ok && len(v) > 0
+}
+
+// substitution is a policy element with its policy variables replaced.
+type substitution struct {
+	text string
+	// literal marks the runes of text produced by variables, which match
+	// only themselves rather than acting as wildcards.
+	literal []bool
+}
+
+// match reports whether s matches the substituted element as a wildcard
+// pattern.
+func (sub substitution) match(s string) bool {
+	return wildcardMatchLiteral([]rune(sub.text), sub.literal, s)
+}
+
+// substitute replaces policy variables such as ${aws:username} in s with
+// values from the context. The special variables ${*}, ${?} and ${$} produce
+// the literal characters. Characters produced by variables are never treated
+// as wildcards, so neither ${*} nor a context value containing '*' matches
+// anything but a '*'. The second return value is false when a variable has
+// no value, in which case the element containing it matches nothing.
+func (ctx evalContext) substitute(s string) (substitution, bool) {
+	if !strings.Contains(s, "${") {
+		return substitution{text: s}, true
+	}
+
+	var sub substitution
+	ok := true
+	last := 0
+	for _, loc := range policyVariableRe.FindAllStringIndex(s, -1) {
+		for range s[last:loc[0]] {
+			sub.literal = append(sub.literal, false)
+		}
+
+		value := ""
+		switch name := s[loc[0]+2 : loc[1]-1]; name {
+		case "*", "?", "$":
+			value = name
+		default:
+			if values, found := ctx.lookup(name); found {
+				value = values[0]
+			} else {
+				ok = false
+			}
+		}
+		for range value {
+			sub.literal = append(sub.literal, true)
+		}
+		sub.text += s[last:loc[0]] + value
+		last = loc[1]
+	}
+	for range s[last:] {
+		sub.literal = append(sub.literal, false)
+	}
+	sub.text += s[last:]
+
+	return sub, ok
+}
+
+// appliesTo reports whether the statement applies to the action and resource,
+// given the request context.
+func (s *Statement) appliesTo(action, resource string,
+	ctx evalContext) (bool, error) {
+	if len(s.Action) > 0 && !anyActionMatch(s.Action, action) {
+		return false, nil
+	}
+	if len(s.NotAction) > 0 && anyActionMatch(s.NotAction, action) {
+		return false, nil
+	}
+	if len(s.Action) == 0 && len(s.NotAction) == 0 {
+		return false, nil
+	}
+
+	switch {
+	case len(s.Resource) > 0:
+		if !ctx.anyResourceMatch(s.Resource, resource) {
+			return false, nil
+		}
+	case len(s.NotResource) > 0:
+		if ctx.anyResourceMatch(s.NotResource, resource) {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+
+	for _, op := range sortedKeys(s.Condition) {
+		for _, key := range sortedKeys(s.Condition[op]) {
+			ok, err := ctx.evalCondition(op, key, s.Condition[op][key])
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+func anyActionMatch(patterns []string, action string) bool {
+	for _, p := range patterns {
+		if actionMatch(p, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ctx evalContext) anyResourceMatch(patterns []string,
+	resource string) bool {
+	for _, p := range patterns {
+		if p == "*" {
+			return true
+		}
+		if sub, ok := ctx.substitute(p); ok && sub.match(resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// evalCondition evaluates a single condition key against the context.
+func (ctx evalContext) evalCondition(op, key string,
+	values []string) (bool, error) {
+	if !knownConditionOperator(op) {
+		return false, fmt.Errorf("unknown condition operator %s", op)
+	}
+
+	qualifier, base, ifExists := splitConditionOperator(op)
+	actual, present := ctx.lookup(key)
+
+	if base == "Null" {
+		if len(values) != 1 {
+			return false, fmt.Errorf("Null condition on %s requires a "+
+				"single value", key)
+		}
+		want, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return false, fmt.Errorf("Null condition on %s: %w", key, err)
+		}
+		return want == !present, nil
+	}
+
+	expected := make([]substitution, 0, len(values))
+	for _, v := range values {
+		if v, ok := ctx.substitute(v); ok {
+			expected = append(expected, v)
+		}
+	}
+
+	negated := negatedConditionOperator(base)
+	if !present {
+		switch {
+		case ifExists:
+			return true, nil
+		case qualifier == "ForAllValues":
+			return true, nil
+		case qualifier == "ForAnyValue":
+			return false, nil
+		}
+		return negated, nil
+	}
+
+	match := func(a string) (bool, error) {
+		for _, e := range expected {
+			ok, err := compareConditionValue(base, a, e)
+			if err != nil {
+				return false, fmt.Errorf("%s condition on %s: %w", op, key, err)
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	// Negated operators are satisfied when no expected value matches, which
+	// is evaluated by matching the positive form and inverting the outcome.
+	if qualifier == "ForAllValues" {
+		for _, a := range actual {
+			ok, err := match(a)
+			if err != nil {
+				return false, err
+			}
+			if ok == negated {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	for _, a := range actual {
+		ok, err := match(a)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return !negated, nil
+		}
+	}
+	return negated, nil
+}
+
+// negatedConditionOperator returns true for operators which are satisfied
+// when the value does not match, such as StringNotEquals.
+func negatedConditionOperator(base string) bool {
+	switch base {
+	case "StringNotEquals", "StringNotEqualsIgnoreCase", "StringNotLike",
+		"NumericNotEquals", "DateNotEquals", "NotIpAddress":
+		return true
+	}
+	return false
+}
+
+// compareConditionValue compares an actual context value with an expected
+// value from the policy using the positive form of the base operator.
+func compareConditionValue(base, actual string,
+	sub substitution) (bool, error) {
+	expected := sub.text
+	switch base {
+	case "StringEquals", "StringNotEquals":
+		return actual == expected, nil
+	case "StringEqualsIgnoreCase", "StringNotEqualsIgnoreCase":
+		return strings.EqualFold(actual, expected), nil
+	case "StringLike", "StringNotLike":
+		return sub.match(actual), nil
+	case "Bool":
+		return strings.EqualFold(actual, expected), nil
+	case "IpAddress", "NotIpAddress":
+		return ipInRange(actual, expected)
+	}
+
+	if strings.HasPrefix(base, "Numeric") {
+		a, err := strconv.ParseFloat(actual, 64)
+		if err != nil {
+			return false, err
+		}
+		e, err := strconv.ParseFloat(expected, 64)
+		if err != nil {
+			return false, err
+		}
+		return compareOrdered(strings.TrimPrefix(base, "Numeric"), a, e), nil
+	}
+
+	if strings.HasPrefix(base, "Date") {
+		a, err := parseConditionDate(actual)
+		if err != nil {
+			return false, err
+		}
+		e, err := parseConditionDate(expected)
+		if err != nil {
+			return false, err
+		}
+		return compareOrdered(strings.TrimPrefix(base, "Date"),
+			float64(a.UnixNano()), float64(e.UnixNano())), nil
+	}
+
+	return false, fmt.Errorf("unsupported condition operator %s", base)
+}
+
+func compareOrdered(cmp string, a, e float64) bool {
+	switch cmp {
+	case "Equals", "NotEquals":
+		return a == e
+	case "LessThan":
+		return a < e
+	case "LessThanEquals":
+		return a <= e
+	case "GreaterThan":
+		return a > e
+	case "GreaterThanEquals":
+		return a >= e
+	}
+	return false
+}
+
+// parseConditionDate accepts dates in RFC3339 format, plain dates or seconds
+// since the epoch.
+func parseConditionDate(s string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// ipInRange returns true when addr is within cidr, which may also be a single
+// address.
+func ipInRange(addr, cidr string) (bool, error) {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false, err
+	}
+
+	if !strings.Contains(cidr, "/") {
+		e, err := netip.ParseAddr(cidr)
+		if err != nil {
+			return false, err
+		}
+		return ip == e, nil
+	}
+
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return false, err
+	}
+	return prefix.Contains(ip), nil
+}
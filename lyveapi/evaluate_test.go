@@ -0,0 +1,201 @@
+package lyveapi
+
+import "testing"
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	perms := []Permission{
+		{
+			Id:      "p1",
+			Name:    "logs-read",
+			Type:    BucketPrefix,
+			Prefix:  "logs-",
+			Actions: ReadOnly,
+		},
+		{
+			Id:      "p2",
+			Name:    "uploads-write",
+			Type:    BucketNames,
+			Buckets: []string{"uploads"},
+			Actions: WriteOnly,
+		},
+		{
+			Id:   "p3",
+			Name: "policy",
+			Type: Policy,
+			Policy: `{"Version":"2012-10-17","Statement":[
+				{"Sid":"home","Effect":"Allow","Action":"s3:*",
+				 "Resource":"arn:aws:s3:::home/${aws:username}/*"},
+				{"Sid":"no-delete","Effect":"Deny","Action":"s3:DeleteObject",
+				 "Resource":"arn:aws:s3:::uploads/retained/*"},
+				{"Sid":"office-only","Effect":"Deny","Action":"s3:*",
+				 "Resource":"arn:aws:s3:::logs-secure*",
+				 "Condition":{"NotIpAddress":{"aws:SourceIp":"10.0.0.0/8"}}}]}`,
+		},
+	}
+
+	type testCase struct {
+		name      string
+		req       AccessRequest
+		allowed   bool
+		effect    Effect
+		permId    string
+		statement int
+	}
+
+	for _, tc := range []testCase{
+		{
+			name:    "prefix-read",
+			req:     AccessRequest{Action: "s3:GetObject", Bucket: "logs-2023", Key: "a.log"},
+			allowed: true, effect: EffectAllow, permId: "p1",
+		},
+		{
+			name:      "prefix-write-implicitly-denied",
+			req:       AccessRequest{Action: "s3:PutObject", Bucket: "logs-2023", Key: "a.log"},
+			statement: -1,
+		},
+		{
+			name:    "bucket-names-write",
+			req:     AccessRequest{Action: "s3:PutObject", Bucket: "uploads", Key: "x/y"},
+			allowed: true, effect: EffectAllow, permId: "p2",
+		},
+		{
+			name:   "explicit-deny-overrides",
+			req:    AccessRequest{Action: "s3:DeleteObject", Bucket: "uploads", Key: "retained/y"},
+			effect: EffectDeny, permId: "p3", statement: 1,
+		},
+		{
+			name: "policy-variable",
+			req: AccessRequest{Action: "s3:PutObject", Bucket: "home", Key: "alice/notes",
+				Context: map[string][]string{"aws:UserName": {"alice"}}},
+			allowed: true, effect: EffectAllow, permId: "p3",
+		},
+		{
+			name: "policy-variable-mismatch",
+			req: AccessRequest{Action: "s3:PutObject", Bucket: "home", Key: "bob/notes",
+				Context: map[string][]string{"aws:username": {"alice"}}},
+			statement: -1,
+		},
+		{
+			name: "condition-deny-outside-network",
+			req: AccessRequest{Action: "s3:GetObject", Bucket: "logs-secure", Key: "k",
+				Context: map[string][]string{"aws:SourceIp": {"203.0.113.9"}}},
+			effect: EffectDeny, permId: "p3", statement: 2,
+		},
+		{
+			name: "condition-allow-inside-network",
+			req: AccessRequest{Action: "s3:GetObject", Bucket: "logs-secure", Key: "k",
+				Context: map[string][]string{"aws:SourceIp": {"10.1.2.3"}}},
+			allowed: true, effect: EffectAllow, permId: "p1",
+		},
+	} {
+		t.Run(tc.name, func(tt *testing.T) {
+			d, err := Evaluate(perms, &tc.req)
+			if err != nil {
+				tt.Fatalf("Unexpected error: %v", err)
+			}
+
+			if d.Allowed != tc.allowed || d.Effect != tc.effect ||
+				d.PermissionId != tc.permId || d.Statement != tc.statement {
+				tt.Errorf("Unexpected decision: %+v", d)
+			}
+		})
+	}
+}
+
+func TestEvaluateConditionOperators(t *testing.T) {
+	t.Parallel()
+
+	ctx := newEvalContext(map[string][]string{
+		"s3:prefix":           {"reports/2023"},
+		"s3:max-keys":         {"100"},
+		"aws:CurrentTime":     {"2023-08-11T19:15:00Z"},
+		"aws:SecureTransport": {"true"},
+	})
+
+	for _, tc := range []struct {
+		op, key string
+		values  []string
+		result  bool
+	}{
+		{"StringLike", "s3:prefix", []string{"reports/*"}, true},
+		{"StringNotLike", "s3:prefix", []string{"reports/*"}, false},
+		{"StringEquals", "s3:delimiter", []string{"/"}, false},
+		{"StringEqualsIfExists", "s3:delimiter", []string{"/"}, true},
+		{"StringNotEquals", "s3:delimiter", []string{"/"}, true},
+		{"NumericLessThanEquals", "s3:max-keys", []string{"100"}, true},
+		{"DateGreaterThan", "aws:CurrentTime", []string{"2023-01-01T00:00:00Z"}, true},
+		{"Bool", "aws:SecureTransport", []string{"false"}, false},
+		{"Null", "s3:delimiter", []string{"true"}, true},
+		{"ForAllValues:StringLike", "s3:prefix", []string{"reports/*", "x/*"}, true},
+	} {
+		ok, err := ctx.evalCondition(tc.op, tc.key, tc.values)
+		if err != nil {
+			t.Errorf("%s %s: unexpected error: %v", tc.op, tc.key, err)
+		} else if ok != tc.result {
+			t.Errorf("%s %s %v: expected %v", tc.op, tc.key, tc.values, tc.result)
+		}
+	}
+
+	if _, err := ctx.evalCondition("StringSortOf", "s3:prefix", nil); err == nil {
+		t.Error("Expected an error for an unknown operator")
+	}
+}
+
+func TestEvaluateAllBucketsAccountLevel(t *testing.T) {
+	t.Parallel()
+
+	perms := []Permission{{Id: "p1", Name: "all", Type: AllBuckets,
+		Actions: ReadOnly}}
+	d, err := Evaluate(perms, &AccessRequest{Action: "s3:ListAllMyBuckets"})
+	if err != nil || !d.Allowed || d.PermissionId != "p1" {
+		t.Errorf("Expected account-level access to be allowed: %+v, %v", d, err)
+	}
+}
+
+func TestEvaluateLiteralVariables(t *testing.T) {
+	t.Parallel()
+
+	perms := []Permission{{
+		Id:   "p1",
+		Name: "policy",
+		Type: Policy,
+		Policy: `{"Statement":[
+			{"Effect":"Allow","Action":"s3:GetObject",
+			 "Resource":"arn:aws:s3:::alpha/${*}/${?}/${aws:username}/*"}]}`,
+	}}
+
+	for _, tc := range []struct {
+		key, user string
+		allowed   bool
+	}{
+		{"*/?/alice/a", "alice", true},
+		{"x/?/alice/a", "alice", false},
+		{"*/y/alice/a", "alice", false},
+		{"*/?/bob/a", "*", false},
+		{"*/?/*/a", "*", true},
+	} {
+		d, err := Evaluate(perms, &AccessRequest{Action: "s3:GetObject",
+			Bucket: "alpha", Key: tc.key,
+			Context: map[string][]string{"aws:username": {tc.user}}})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.key, err)
+		}
+		if d.Allowed != tc.allowed {
+			t.Errorf("%s as %s: expected allowed %v", tc.key, tc.user, tc.allowed)
+		}
+	}
+
+	ctx := newEvalContext(map[string][]string{"s3:prefix": {"a*"}})
+	if ok, _ := ctx.evalCondition("StringLike", "s3:prefix",
+		[]string{"${s3:prefix}"}); !ok {
+		t.Error("Expected a substituted value to match itself")
+	}
+	ctx = newEvalContext(map[string][]string{"s3:prefix": {"ab"},
+		"aws:username": {"a*"}})
+	if ok, _ := ctx.evalCondition("StringLike", "s3:prefix",
+		[]string{"${aws:username}"}); ok {
+		t.Error("Expected a substituted '*' not to act as a wildcard")
+	}
+}
@@ -0,0 +1,82 @@
+package lyveapi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SetExpiration sets the time at which the service account will expire.
+func (r *CreateServiceAcctReq) SetExpiration(t time.Time) {
+	r.ExpirationDate = NewTimestamp(t)
+}
+
+// ExpirationTime returns the time at which the service account expires, or
+// the zero time if it never expires.
+func (r *CreateServiceAcctResp) ExpirationTime() (time.Time, error) {
+	return r.ExpirationDate.Time()
+}
+
+// ExpirationTime returns the time at which the service account expires, or
+// the zero time if it never expires.
+func (s *ServiceAcct) ExpirationTime() (time.Time, error) {
+	return s.ExpirationDate.Time()
+}
+
+// ExpiringBefore returns the service accounts in the list which expire before
+// deadline, including those which have already expired, ordered by expiration
+// time. Accounts which never expire are omitted. Accounts whose expiration
+// date cannot be parsed are also omitted, and reported together in the error.
+func (l ServiceAcctList) ExpiringBefore(
+	deadline time.Time) (ServiceAcctList, error) {
+	var out ServiceAcctList
+	var expires []time.Time
+	var errs []error
+
+	for _, acct := range l {
+		t, err := acct.ExpirationTime()
+		if err != nil {
+			errs = append(errs, fmt.Errorf(
+				"service account %s: invalid expiration date %q",
+				acct.Id, acct.ExpirationDate))
+			continue
+		}
+		if !t.IsZero() && t.Before(deadline) {
+			out = append(out, acct)
+			expires = append(expires, t)
+		}
+	}
+
+	sort.Sort(byExpiration{out, expires})
+	return out, errors.Join(errs...)
+}
+
+type byExpiration struct {
+	accts   ServiceAcctList
+	expires []time.Time
+}
+
+func (b byExpiration) Len() int { return len(b.accts) }
+
+func (b byExpiration) Less(i, j int) bool {
+	return b.expires[i].Before(b.expires[j])
+}
+
+func (b byExpiration) Swap(i, j int) {
+	b.accts[i], b.accts[j] = b.accts[j], b.accts[i]
+	b.expires[i], b.expires[j] = b.expires[j], b.expires[i]
+}
+
+// ServiceAccountsExpiringWithin lists the service accounts and returns those
+// which expire within the given window from now, or have already expired, so
+// that they can be renewed or rotated before workloads depending on them
+// break. See ServiceAcctList.ExpiringBefore.
+func (client *Client) ServiceAccountsExpiringWithin(
+	window time.Duration) (ServiceAcctList, error) {
+	accts, err := client.ListServiceAccounts()
+	if err != nil {
+		return nil, err
+	}
+	return accts.ExpiringBefore(time.Now().Add(window))
+}
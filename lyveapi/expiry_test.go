@@ -0,0 +1,96 @@
+package lyveapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestServiceAcctExpirationTime(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		date     Timestamp
+		expected time.Time
+		fails    bool
+	}{
+		{"", time.Time{}, false},
+		{"2030-01-02T03:04:05Z", time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{"2030-01-02T03:04:05.250Z",
+			time.Date(2030, 1, 2, 3, 4, 5, 250e6, time.UTC), false},
+		{"2030-01-02", time.Time{}, true},
+	}
+
+	for _, test := range tests {
+		got, err := (&ServiceAcct{ExpirationDate: test.date}).ExpirationTime()
+		if (err != nil) != test.fails || !got.Equal(test.expected) {
+			t.Errorf("%q: unexpected result %v, %v", test.date, got, err)
+		}
+	}
+}
+
+func TestCreateServiceAcctReqExpiration(t *testing.T) {
+	t.Parallel()
+
+	req := &CreateServiceAcctReq{Name: "a", Permissions: []string{"p"}}
+	req.SetExpiration(time.Now().Add(-time.Hour))
+
+	var vErr *ValidationError
+	if err := req.Validate(); !errors.As(err, &vErr) ||
+		vErr.Fields[0].Field != "expirationDate" {
+		t.Errorf("Expected a past expiration to be rejected; got %v", err)
+	}
+
+	f, client := newFakeApi(t)
+	req.Permissions = []string{
+		f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})}
+	expires := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	req.SetExpiration(expires)
+
+	resp, err := client.CreateServiceAccount(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, err := resp.ExpirationTime(); err != nil || !got.Equal(expires) {
+		t.Errorf("Unexpected expiration: %v, %v", got, err)
+	}
+	if got, err := f.serviceAccount(resp.Id).ExpirationTime(); err != nil ||
+		!got.Equal(expires) {
+		t.Errorf("Unexpected stored expiration: %v, %v", got, err)
+	}
+}
+
+func TestServiceAccountsExpiringWithin(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	at := func(d time.Duration) Timestamp {
+		return NewTimestamp(time.Now().Add(d))
+	}
+	f.addServiceAccount(ServiceAcct{Name: "never"})
+	later := f.addServiceAccount(ServiceAcct{Name: "later", ExpirationDate: at(72 * time.Hour)})
+	soon := f.addServiceAccount(ServiceAcct{Name: "soon", ExpirationDate: at(time.Hour)})
+	expired := f.addServiceAccount(ServiceAcct{Name: "expired", ExpirationDate: at(-time.Hour)})
+	f.addServiceAccount(ServiceAcct{Name: "far", ExpirationDate: at(30 * 24 * time.Hour)})
+
+	accts, err := client.ServiceAccountsExpiringWithin(7 * 24 * time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{expired, soon, later}
+	if len(accts) != len(expected) {
+		t.Fatalf("Unexpected accounts: %+v", accts)
+	}
+	for i, id := range expected {
+		if accts[i].Id != id {
+			t.Errorf("Expected %s at %d; got %s", id, i, accts[i].Id)
+		}
+	}
+
+	f.addServiceAccount(ServiceAcct{Name: "bad", ExpirationDate: "tomorrow"})
+	if accts, err = client.ServiceAccountsExpiringWithin(
+		7 * 24 * time.Hour); err == nil || len(accts) != 3 {
+		t.Errorf("Expected the invalid date to be reported; got %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+package lyveapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeApi is an in-memory implementation of the permission and service account
+// endpoints of the API, used to test client methods which make several
+// requests. It models the behaviour of the API without reusing client code,
+// so that tests can check what the client stored and sent.
+type fakeApi struct {
+	mtx    sync.Mutex
+	perms  map[string]*Permission
+	accts  map[string]*ServiceAcct
+	nextId int
+
+	// pendingReads is the number of GET requests for which a newly created
+	// object reports that it is not ready.
+	pendingReads int
+	notReady     map[string]int
+
+	// requests records each request as "METHOD path".
+	requests []string
+
+	// before, when set, is called with the lock held before each request is
+	// handled. Returning false means the request was handled by the hook.
+	before func(w http.ResponseWriter, r *http.Request) bool
+}
+
+func newFakeApi(t *testing.T) (*fakeApi, *Client) {
+	f := &fakeApi{
+		perms:    map[string]*Permission{},
+		accts:    map[string]*ServiceAcct{},
+		notReady: map[string]int{},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(srv.Close)
+
+	return f, &Client{apiUrl: srv.URL}
+}
+
+func (f *fakeApi) addPermission(p Permission) string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	p.Id = f.newId("perm")
+	p.ReadyState = true
+	f.perms[p.Id] = &p
+	return p.Id
+}
+
+func (f *fakeApi) addServiceAccount(a ServiceAcct) string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	a.Id = f.newId("sa")
+	a.ReadyState = true
+	f.accts[a.Id] = &a
+	return a.Id
+}
+
+func (f *fakeApi) permission(id string) *Permission {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.perms[id]
+}
+
+func (f *fakeApi) serviceAccount(id string) *ServiceAcct {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.accts[id]
+}
+
+// count returns the number of requests made with the given method and path
+// prefix.
+func (f *fakeApi) count(method, prefix string) int {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	n := 0
+	for _, r := range f.requests {
+		if strings.HasPrefix(r, method+" "+prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeApi) newId(kind string) string {
+	f.nextId++
+	return kind + "-" + strconv.Itoa(f.nextId)
+}
+
+func (f *fakeApi) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	if f.before != nil && !f.before(w, r) {
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch parts[0] {
+	case "permissions":
+		f.servePermissions(w, r, parts[1:])
+	case "service-accounts":
+		f.serveServiceAccounts(w, r, parts[1:])
+	default:
+		fakeError(w, http.StatusNotFound, "NotFound")
+	}
+}
+
+func (f *fakeApi) servePermissions(
+	w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			list := PermissionList{}
+			for _, id := range sortedKeys(f.perms) {
+				list = append(list, *f.perms[id])
+			}
+			fakeJSON(w, list)
+		case http.MethodPost:
+			p := &Permission{}
+			_ = json.NewDecoder(r.Body).Decode(p)
+			for _, existing := range f.perms {
+				if existing.Name == p.Name {
+					fakeError(w, http.StatusConflict,
+						"PermissionNameAlreadyExists")
+					return
+				}
+			}
+			p.Id = f.newId("perm")
+			p.ReadyState = f.pendingReads == 0
+			f.notReady[p.Id] = f.pendingReads
+			f.perms[p.Id] = p
+			fakeJSON(w, p)
+		}
+		return
+	}
+
+	p, ok := f.perms[parts[0]]
+	if !ok {
+		fakeError(w, http.StatusNotFound, "PermissionNotFound")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		f.tickReady(p.Id, &p.ReadyState)
+		fakeJSON(w, p)
+	case http.MethodPut:
+		f.perms[p.Id] = fakeUpdatePermission(p, r)
+	case http.MethodDelete:
+		delete(f.perms, p.Id)
+	}
+}
+
+// fakeUpdatePermission returns p updated by the body of a PUT request as the
+// API does: only the fields present in the body are changed, and when the
+// type changes, the fields of the old type which the body does not set are
+// cleared.
+func fakeUpdatePermission(p *Permission, r *http.Request) *Permission {
+	var fields map[string]json.RawMessage
+	_ = json.NewDecoder(r.Body).Decode(&fields)
+
+	update := *p
+	if raw, ok := fields["type"]; ok {
+		_ = json.Unmarshal(raw, &update.Type)
+		if update.Type != p.Type {
+			update.Actions, update.Prefix = "", ""
+			update.Buckets, update.Policy = nil, ""
+			if update.Type != Policy {
+				update.Actions = p.Actions
+			}
+		}
+	}
+
+	targets := map[string]interface{}{
+		"name":        &update.Name,
+		"description": &update.Description,
+		"actions":     &update.Actions,
+		"prefix":      &update.Prefix,
+		"buckets":     &update.Buckets,
+		"policy":      &update.Policy,
+	}
+	for key, target := range targets {
+		if raw, ok := fields[key]; ok {
+			_ = json.Unmarshal(raw, target)
+		}
+	}
+	return &update
+}
+
+func (f *fakeApi) serveServiceAccounts(
+	w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			list := ServiceAcctList{}
+			for _, id := range sortedKeys(f.accts) {
+				a := *f.accts[id]
+				a.Permissions = nil
+				list = append(list, a)
+			}
+			fakeJSON(w, list)
+		case http.MethodPost:
+			req := &CreateServiceAcctReq{}
+			_ = json.NewDecoder(r.Body).Decode(req)
+			for _, existing := range f.accts {
+				if existing.Name == req.Name {
+					fakeError(w, http.StatusConflict,
+						"ServiceAccountNameAlreadyExists")
+					return
+				}
+			}
+			for _, id := range req.Permissions {
+				if f.perms[id] == nil {
+					fakeError(w, http.StatusBadRequest, "InvalidPermissions")
+					return
+				}
+			}
+			a := &ServiceAcct{
+				Id:             f.newId("sa"),
+				Name:           req.Name,
+				Description:    req.Description,
+				Enabled:        true,
+				ReadyState:     f.pendingReads == 0,
+				Permissions:    req.Permissions,
+				ExpirationDate: req.ExpirationDate,
+			}
+			f.notReady[a.Id] = f.pendingReads
+			f.accts[a.Id] = a
+			fakeJSON(w, &CreateServiceAcctResp{
+				Id:             a.Id,
+				AccessKey:      "access-" + a.Id,
+				Secret:         "secret-" + a.Id,
+				ExpirationDate: a.ExpirationDate,
+			})
+		}
+		return
+	}
+
+	a, ok := f.accts[parts[0]]
+	if !ok {
+		fakeError(w, http.StatusNotFound, "ServiceAccountNotFound")
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "enabled" {
+		a.Enabled = r.Method == http.MethodPut
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		f.tickReady(a.Id, &a.ReadyState)
+		fakeJSON(w, a)
+	case http.MethodPut:
+		// Only the fields present in the body are changed.
+		update := *a
+		update.Permissions = append([]string(nil), a.Permissions...)
+		_ = json.NewDecoder(r.Body).Decode(&update)
+		update.Id, update.ReadyState = a.Id, a.ReadyState
+		f.accts[a.Id] = &update
+	case http.MethodDelete:
+		delete(f.accts, a.Id)
+	}
+}
+
+// tickReady counts down the reads for which an object is not ready.
+func (f *fakeApi) tickReady(id string, ready *bool) {
+	if f.notReady[id] > 0 {
+		f.notReady[id]--
+	}
+	*ready = f.notReady[id] == 0
+}
+
+func fakeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func fakeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"code":    code,
+		"message": code,
+	})
+}
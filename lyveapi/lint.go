@@ -0,0 +1,547 @@
+package lyveapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Severity ranks how serious a lint finding is.
+type Severity uint8
+
+const (
+	// SeverityInfo findings are observations which do not require action.
+	SeverityInfo Severity = iota
+	// SeverityWarning findings are likely mistakes or overly broad grants.
+	SeverityWarning
+	// SeverityError findings describe policies which will not work as intended
+	// or will be rejected by the API.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "unknown"
+}
+
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Lint rule identifiers. These are stable and may be used to filter or
+// suppress findings.
+const (
+	// LintRuleInvalidDocument reports policy JSON which cannot be decoded or
+	// which is structurally invalid.
+	LintRuleInvalidDocument = "LC000"
+	// LintRuleUnknownAction reports actions which match no known S3 action.
+	LintRuleUnknownAction = "LC001"
+	// LintRuleMalformedResource reports resources which are not well-formed
+	// S3 bucket or object ARNs.
+	LintRuleMalformedResource = "LC002"
+	// LintRuleWildcardEverything reports statements allowing all actions on
+	// all resources.
+	LintRuleWildcardEverything = "LC003"
+	// LintRuleAllowWithoutResource reports Allow statements which do not name
+	// any resources.
+	LintRuleAllowWithoutResource = "LC004"
+	// LintRuleShadowedByDeny reports Allow statements which can never take
+	// effect, since an unconditional Deny statement covers all of their
+	// actions and resources.
+	LintRuleShadowedByDeny = "LC005"
+	// LintRuleUnsupportedConditionKey reports condition keys which are not
+	// supported in Lyve Cloud policies.
+	LintRuleUnsupportedConditionKey = "LC006"
+	// LintRuleUnknownConditionOperator reports unknown condition operators.
+	LintRuleUnknownConditionOperator = "LC007"
+	// LintRulePrincipal reports statements with a Principal or NotPrincipal,
+	// which do not apply to permissions attached to service accounts.
+	LintRulePrincipal = "LC008"
+	// LintRuleInvalidPermission reports structured permissions whose fields
+	// fail validation.
+	LintRuleInvalidPermission = "LC009"
+)
+
+// ErrLintFailed is matched by errors.Is for any *LintError.
+var ErrLintFailed = errors.New("policy lint failed")
+
+// conditionOperators lists the supported condition operators, without any
+// "ForAnyValue:" or "ForAllValues:" qualifier and "IfExists" suffix.
+var conditionOperators = map[string]bool{
+	"StringEquals":              true,
+	"StringNotEquals":           true,
+	"StringEqualsIgnoreCase":    true,
+	"StringNotEqualsIgnoreCase": true,
+	"StringLike":                true,
+	"StringNotLike":             true,
+	"NumericEquals":             true,
+	"NumericNotEquals":          true,
+	"NumericLessThan":           true,
+	"NumericLessThanEquals":     true,
+	"NumericGreaterThan":        true,
+	"NumericGreaterThanEquals":  true,
+	"DateEquals":                true,
+	"DateNotEquals":             true,
+	"DateLessThan":              true,
+	"DateLessThanEquals":        true,
+	"DateGreaterThan":           true,
+	"DateGreaterThanEquals":     true,
+	"Bool":                      true,
+	"IpAddress":                 true,
+	"NotIpAddress":              true,
+	"Null":                      true,
+}
+
+// conditionKeys lists the condition keys supported in Lyve Cloud policies,
+// in lowercase, since condition keys are case-insensitive.
+var conditionKeys = map[string]bool{
+	"aws:currenttime":           true,
+	"aws:epochtime":             true,
+	"aws:referer":               true,
+	"aws:securetransport":       true,
+	"aws:sourceip":              true,
+	"aws:useragent":             true,
+	"aws:userid":                true,
+	"aws:username":              true,
+	"s3:delimiter":              true,
+	"s3:locationconstraint":     true,
+	"s3:max-keys":               true,
+	"s3:object-lock-legal-hold": true,
+	"s3:object-lock-mode":       true,
+	"s3:object-lock-remaining-retention-days": true,
+	"s3:object-lock-retain-until-date":        true,
+	"s3:prefix":                               true,
+	"s3:requestobjecttagkeys":                 true,
+	"s3:signatureversion":                     true,
+	"s3:versionid":                            true,
+	"s3:x-amz-acl":                            true,
+	"s3:x-amz-content-sha256":                 true,
+	"s3:x-amz-copy-source":                    true,
+	"s3:x-amz-metadata-directive":             true,
+	"s3:x-amz-server-side-encryption":         true,
+	"s3:x-amz-storage-class":                  true,
+}
+
+// conditionKeyPrefixes lists supported condition keys which are followed by a
+// user-defined part, such as a tag key.
+var conditionKeyPrefixes = []string{
+	"s3:existingobjecttag/",
+	"s3:requestobjecttag/",
+}
+
+// policyVariableRe matches policy variables such as ${aws:username}.
+var policyVariableRe = regexp.MustCompile(`\$\{[^}]*\}`)
+
+// resourceBucketRe describes the bucket part of an S3 ARN once any policy
+// variables have been removed. Wildcards are permitted.
+var resourceBucketRe = regexp.MustCompile(`^[a-z0-9.*?-]+$`)
+
+// splitConditionOperator separates an operator such as
+// "ForAnyValue:StringLikeIfExists" into its qualifier, base operator and
+// whether the "IfExists" suffix is present.
+func splitConditionOperator(op string) (qualifier, base string, ifExists bool) {
+	if i := strings.Index(op, ":"); i >= 0 {
+		qualifier, op = op[:i], op[i+1:]
+	}
+
+	if op != "IfExists" && strings.HasSuffix(op, "IfExists") {
+		op, ifExists = strings.TrimSuffix(op, "IfExists"), true
+	}
+
+	return qualifier, op, ifExists
+}
+
+// knownConditionOperator returns true for supported condition operators.
+func knownConditionOperator(op string) bool {
+	qualifier, base, ifExists := splitConditionOperator(op)
+	switch qualifier {
+	case "", "ForAnyValue", "ForAllValues":
+	default:
+		return false
+	}
+
+	// The Null operator has no "IfExists" variant.
+	if base == "Null" && ifExists {
+		return false
+	}
+
+	return conditionOperators[base]
+}
+
+// supportedConditionKey returns true for condition keys supported in Lyve
+// Cloud policies.
+func supportedConditionKey(key string) bool {
+	key = strings.ToLower(key)
+	if conditionKeys[key] {
+		return true
+	}
+
+	for _, p := range conditionKeyPrefixes {
+		if strings.HasPrefix(key, p) && len(key) > len(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// LintFinding describes a single issue found by the linter.
+type LintFinding struct {
+	// RuleId is the stable identifier of the rule, such as "LC001".
+	RuleId string `json:"ruleId"`
+	// Severity ranks the finding.
+	Severity Severity `json:"severity"`
+	// Statement is the index of the offending statement, or -1 when the
+	// finding applies to the permission or document as a whole.
+	Statement int `json:"statement"`
+	// Sid is the identifier of the offending statement, if it has one.
+	Sid string `json:"sid,omitempty"`
+	// Message describes the issue.
+	Message string `json:"message"`
+}
+
+func (f LintFinding) String() string {
+	var where string
+	switch {
+	case f.Statement < 0:
+	case f.Sid != "":
+		where = fmt.Sprintf(" statement %d (%s)", f.Statement, f.Sid)
+	default:
+		where = fmt.Sprintf(" statement %d", f.Statement)
+	}
+
+	return f.Severity.String() + " " + f.RuleId + where + ": " + f.Message
+}
+
+// LintFindings is a list of findings produced by the linter.
+type LintFindings []LintFinding
+
+// MaxSeverity returns the highest severity among the findings, or SeverityInfo
+// when there are none.
+func (fs LintFindings) MaxSeverity() Severity {
+	max := SeverityInfo
+	for _, f := range fs {
+		if f.Severity > max {
+			max = f.Severity
+		}
+	}
+	return max
+}
+
+// AtLeast returns the findings whose severity is at least min.
+func (fs LintFindings) AtLeast(min Severity) LintFindings {
+	var out LintFindings
+	for _, f := range fs {
+		if f.Severity >= min {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Err returns a *LintError when there are findings with a severity of at least
+// min, otherwise nil. This is intended for gating CreatePermission and
+// UpdatePermission calls, for example in CI:
+//
+//	if err := LintPermission(perm).Err(SeverityWarning); err != nil {
+//		return err
+//	}
+func (fs LintFindings) Err(min Severity) error {
+	if failed := fs.AtLeast(min); len(failed) > 0 {
+		return &LintError{Findings: failed}
+	}
+	return nil
+}
+
+// LintError is returned by LintFindings.Err and carries the findings which
+// failed the check.
+type LintError struct {
+	Findings LintFindings
+}
+
+func (e *LintError) Error() string {
+	msgs := make([]string, len(e.Findings))
+	for i, f := range e.Findings {
+		msgs[i] = f.String()
+	}
+	return "policy lint failed: " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether target is ErrLintFailed.
+func (e *LintError) Is(target error) bool {
+	return target == ErrLintFailed
+}
+
+// linter accumulates findings.
+type linter struct {
+	findings LintFindings
+}
+
+func (l *linter) add(rule string, sev Severity, idx int, sid,
+	format string, args ...interface{}) {
+	l.findings = append(l.findings, LintFinding{
+		RuleId:    rule,
+		Severity:  sev,
+		Statement: idx,
+		Sid:       sid,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+// LintPermission inspects a permission and reports any issues found. For
+// permissions of type "policy" the policy document is linted, whereas other
+// permission types are checked for invalid fields and overly broad grants.
+func LintPermission(p *Permission) LintFindings {
+	l := &linter{}
+
+	if p.IsPolicyPermission() {
+		if strings.TrimSpace(p.Policy) == "" {
+			l.add(LintRuleInvalidPermission, SeverityError, -1, "",
+				"%s", PolicyMissingErrMsg)
+			return l.findings
+		}
+		return LintPolicyJSON(p.Policy)
+	}
+
+	var vErr *ValidationError
+	if err := p.Validate(); errors.As(err, &vErr) {
+		for _, f := range vErr.Fields {
+			l.add(LintRuleInvalidPermission, SeverityError, -1, "", "%s", f)
+		}
+	}
+
+	if p.Type == AllBuckets && p.Actions == AllOperations {
+		l.add(LintRuleWildcardEverything, SeverityWarning, -1, "",
+			"permission allows all operations on all buckets")
+	}
+
+	return l.findings
+}
+
+// LintPolicyJSON decodes and lints a JSON policy document. A document which
+// cannot be decoded results in a single LintRuleInvalidDocument finding.
+func LintPolicyJSON(policy string) LintFindings {
+	doc, err := ParsePolicyDocument(policy)
+	if err != nil {
+		l := &linter{}
+		l.add(LintRuleInvalidDocument, SeverityError, -1, "", "%v", err)
+		return l.findings
+	}
+	return LintPolicy(doc)
+}
+
+// LintPolicy inspects a policy document and reports any issues found. Findings
+// are ordered by statement.
+func LintPolicy(doc *PolicyDocument) LintFindings {
+	l := &linter{}
+
+	// A missing resource of an Allow statement is reported by the more
+	// specific LintRuleAllowWithoutResource rather than as invalid.
+	specific := make(map[string]bool)
+	for i := range doc.Statement {
+		if allowWithoutResource(&doc.Statement[i]) {
+			specific[fmt.Sprintf("Statement[%d].Resource", i)] = true
+		}
+	}
+
+	var vErr *ValidationError
+	if err := doc.Validate(); errors.As(err, &vErr) {
+		for _, f := range vErr.Fields {
+			if !specific[f.Field] {
+				l.add(LintRuleInvalidDocument, SeverityError, -1, "", "%s", f)
+			}
+		}
+	}
+
+	for i := range doc.Statement {
+		l.lintStatement(doc, i)
+	}
+
+	return l.findings
+}
+
+func (l *linter) lintStatement(doc *PolicyDocument, idx int) {
+	s := &doc.Statement[idx]
+
+	for _, a := range s.Action {
+		l.lintAction(idx, s.Sid, a)
+	}
+	for _, a := range s.NotAction {
+		l.lintAction(idx, s.Sid, a)
+	}
+
+	for _, r := range s.Resource {
+		l.lintResource(idx, s.Sid, r)
+	}
+	for _, r := range s.NotResource {
+		l.lintResource(idx, s.Sid, r)
+	}
+
+	if s.Principal != nil || s.NotPrincipal != nil {
+		l.add(LintRulePrincipal, SeverityWarning, idx, s.Sid,
+			"Principal and NotPrincipal are not supported in permission "+
+				"policies, which apply to the service accounts they are "+
+				"attached to")
+	}
+
+	for _, op := range sortedKeys(s.Condition) {
+		if !knownConditionOperator(op) {
+			l.add(LintRuleUnknownConditionOperator, SeverityWarning, idx, s.Sid,
+				"unknown condition operator %s", op)
+		}
+		for _, key := range sortedKeys(s.Condition[op]) {
+			if !supportedConditionKey(key) {
+				l.add(LintRuleUnsupportedConditionKey, SeverityWarning, idx,
+					s.Sid, "unsupported condition key %s", key)
+			}
+		}
+	}
+
+	if s.Effect != EffectAllow {
+		return
+	}
+
+	if allowWithoutResource(s) {
+		l.add(LintRuleAllowWithoutResource, SeverityError, idx, s.Sid,
+			"Allow statement does not name any resources")
+	}
+
+	if len(s.Condition) == 0 && statementAllowsEverything(s) {
+		l.add(LintRuleWildcardEverything, SeverityWarning, idx, s.Sid,
+			"statement allows all actions on all resources")
+	}
+
+	if d := shadowingDeny(doc, idx); d >= 0 {
+		l.add(LintRuleShadowedByDeny, SeverityWarning, idx, s.Sid,
+			"statement has no effect, all of its actions and resources are "+
+				"denied by statement %d", d)
+	}
+}
+
+// allowWithoutResource returns true for an Allow statement which names no
+// resources.
+func allowWithoutResource(s *Statement) bool {
+	return s.Effect == EffectAllow &&
+		len(s.Resource) == 0 && len(s.NotResource) == 0
+}
+
+func (l *linter) lintAction(idx int, sid, action string) {
+	if action == "*" {
+		return
+	}
+
+	service, name, found := strings.Cut(action, ":")
+	switch {
+	case !found || name == "":
+		l.add(LintRuleUnknownAction, SeverityError, idx, sid,
+			"malformed action %q; expected service:action", action)
+	case !strings.EqualFold(service, "s3"):
+		l.add(LintRuleUnknownAction, SeverityError, idx, sid,
+			"action %q belongs to unsupported service %s", action, service)
+	case len(expandActionPattern(action)) == 0:
+		l.add(LintRuleUnknownAction, SeverityError, idx, sid,
+			"unknown S3 action %q", action)
+	}
+}
+
+func (l *linter) lintResource(idx int, sid, resource string) {
+	if resource == "*" {
+		return
+	}
+
+	if !strings.HasPrefix(resource, s3ArnPrefix) {
+		l.add(LintRuleMalformedResource, SeverityError, idx, sid,
+			"resource %q is not an S3 ARN of the form %sbucket/key",
+			resource, s3ArnPrefix)
+		return
+	}
+
+	rest := strings.TrimPrefix(resource, s3ArnPrefix)
+	bucket, _, _ := strings.Cut(rest, "/")
+	bucket = policyVariableRe.ReplaceAllString(bucket, "x")
+	if bucket == "" || !resourceBucketRe.MatchString(bucket) {
+		l.add(LintRuleMalformedResource, SeverityError, idx, sid,
+			"resource %q does not name a valid bucket", resource)
+	}
+}
+
+// statementAllowsEverything returns true when s applies to all actions on all
+// resources.
+func statementAllowsEverything(s *Statement) bool {
+	if len(s.NotAction) > 0 || len(s.NotResource) > 0 {
+		return false
+	}
+
+	var allActions, allResources bool
+	for _, a := range s.Action {
+		if a == "*" || strings.EqualFold(a, "s3:*") {
+			allActions = true
+		}
+	}
+	for _, r := range s.Resource {
+		if r == "*" || r == s3ArnPrefix+"*" {
+			allResources = true
+		}
+	}
+	return allActions && allResources
+}
+
+// shadowingDeny returns the index of an unconditional Deny statement which
+// covers every action and resource of the Allow statement at idx, or -1 if
+// there is no such statement. Only statements using Action and Resource are
+// considered.
+func shadowingDeny(doc *PolicyDocument, idx int) int {
+	allow := &doc.Statement[idx]
+	if len(allow.Action) == 0 || len(allow.Resource) == 0 {
+		return -1
+	}
+
+	for i := range doc.Statement {
+		deny := &doc.Statement[i]
+		if deny.Effect != EffectDeny || len(deny.Condition) > 0 ||
+			len(deny.Action) == 0 || len(deny.Resource) == 0 {
+			continue
+		}
+
+		if patternsCovered(deny.Action, allow.Action, true) &&
+			patternsCovered(deny.Resource, allow.Resource, false) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// patternsCovered returns true when every pattern in inner is matched by some
+// pattern in outer. Wildcards in inner are treated as literal characters,
+// which means an inner wildcard is only covered by an outer wildcard. Since an
+// inner '*' may stand for any number of characters, it is not covered by an
+// outer '?'. Patterns are compared case-insensitively when fold is set, as
+// action names are.
+func patternsCovered(outer, inner []string, fold bool) bool {
+	for _, in := range inner {
+		var covered bool
+		for _, out := range outer {
+			if fold {
+				out, in = strings.ToLower(out), strings.ToLower(in)
+			}
+			if out == "*" || wildcardMatchRunes(
+				[]rune(out), nil, []rune(in), '*') {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package lyveapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLintPolicyJSON(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		name   string
+		policy string
+		rules  []string
+	}
+
+	for _, tc := range []testCase{
+		{
+			name: "clean",
+			policy: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow",
+				"Action":["s3:Get*","s3:ListBucket"],
+				"Resource":["arn:aws:s3:::alpha","arn:aws:s3:::alpha/*"]}]}`,
+		},
+		{
+			name:   "not-json",
+			policy: `{"Statement": [`,
+			rules:  []string{LintRuleInvalidDocument},
+		},
+		{
+			name: "unknown-action",
+			policy: `{"Statement":[{"Effect":"Allow","Action":["s3:GetObjekt",
+				"ec2:RunInstances"],"Resource":"arn:aws:s3:::alpha/*"}]}`,
+			rules: []string{LintRuleUnknownAction, LintRuleUnknownAction},
+		},
+		{
+			name: "malformed-arn",
+			policy: `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject",
+				"Resource":["arn:aws:s3::alpha/*","arn:aws:s3:::Alpha_Bucket/*",
+				"arn:aws:s3:::home/${aws:username}/*"]}]}`,
+			rules: []string{LintRuleMalformedResource, LintRuleMalformedResource},
+		},
+		{
+			name: "wildcard-everything",
+			policy: `{"Statement":[{"Effect":"Allow","Action":"s3:*",
+				"Resource":"*"}]}`,
+			rules: []string{LintRuleWildcardEverything},
+		},
+		{
+			name:   "allow-without-resource",
+			policy: `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject"}]}`,
+			rules:  []string{LintRuleAllowWithoutResource},
+		},
+		{
+			name:   "deny-without-resource",
+			policy: `{"Statement":[{"Effect":"Deny","Action":"s3:GetObject"}]}`,
+			rules:  []string{LintRuleInvalidDocument},
+		},
+		{
+			name: "shadowed-by-deny",
+			policy: `{"Statement":[
+				{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::alpha/logs/*"},
+				{"Effect":"Deny","Action":"s3:Get*","Resource":"arn:aws:s3:::alpha/*"}]}`,
+			rules: []string{LintRuleShadowedByDeny},
+		},
+		{
+			name: "single-character-deny-does-not-shadow",
+			policy: `{"Statement":[
+				{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::alpha/*"},
+				{"Effect":"Deny","Action":"s3:GetObject","Resource":"arn:aws:s3:::alpha/?"}]}`,
+		},
+		{
+			name: "conditional-deny-does-not-shadow",
+			policy: `{"Statement":[
+				{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::alpha/*"},
+				{"Effect":"Deny","Action":"s3:*","Resource":"arn:aws:s3:::alpha/*",
+				 "Condition":{"NotIpAddress":{"aws:SourceIp":"10.0.0.0/8"}}}]}`,
+		},
+		{
+			name: "unsupported-condition",
+			policy: `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject",
+				"Resource":"arn:aws:s3:::alpha/*",
+				"Condition":{"StringEquals":{"aws:PrincipalOrgID":"o-123"},
+				"StringSortOf":{"s3:prefix":"x"}}}]}`,
+			rules: []string{LintRuleUnsupportedConditionKey,
+				LintRuleUnknownConditionOperator},
+		},
+	} {
+		t.Run(tc.name, func(tt *testing.T) {
+			findings := LintPolicyJSON(tc.policy)
+			if len(findings) != len(tc.rules) {
+				tt.Fatalf("Expected %d findings; got %v", len(tc.rules), findings)
+			}
+			for i, r := range tc.rules {
+				if findings[i].RuleId != r {
+					tt.Errorf("Expected rule %s; got %s", r, findings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLintFindingsErr(t *testing.T) {
+	t.Parallel()
+
+	p := &Permission{Name: "all", Type: AllBuckets, Actions: AllOperations}
+	findings := LintPermission(p)
+
+	if findings.MaxSeverity() != SeverityWarning {
+		t.Errorf("Expected a warning; got %v", findings)
+	}
+
+	if err := findings.Err(SeverityError); err != nil {
+		t.Errorf("Expected no error at error severity; got %v", err)
+	}
+
+	err := findings.Err(SeverityWarning)
+	var lintErr *LintError
+	if !errors.As(err, &lintErr) || !errors.Is(err, ErrLintFailed) {
+		t.Errorf("Expected a *LintError; got %v", err)
+	}
+}
+
+func Test_wildcardMatch(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		pattern, s string
+		match      bool
+	}{
+		{"*", "", true},
+		{"s3:Get*", "s3:GetObject", true},
+		{"s3:Get*", "s3:PutObject", false},
+		{"a?c", "abc", true},
+		{"*a", "*ba", true},
+		{"arn:aws:s3:::logs-*/*", "arn:aws:s3:::logs-2023/x/y", true},
+		{"arn:aws:s3:::logs-*/*", "arn:aws:s3:::logs-2023", false},
+	} {
+		if wildcardMatch(tc.pattern, tc.s) != tc.match {
+			t.Errorf("wildcardMatch(%q, %q) != %v", tc.pattern, tc.s, tc.match)
+		}
+	}
+}
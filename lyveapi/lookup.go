@@ -0,0 +1,185 @@
+package lyveapi
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNameNotFound is matched by errors.Is when no object has the name
+	// being looked up.
+	ErrNameNotFound = errors.New("no object has the given name")
+	// ErrAmbiguousName is matched by errors.Is when more than one object has
+	// the name being looked up.
+	ErrAmbiguousName = errors.New("more than one object has the given name")
+)
+
+// Kinds of objects reported by NameLookupError.
+const (
+	PermissionKind     = "permission"
+	ServiceAccountKind = "service account"
+)
+
+// NameLookupError is returned when a name does not identify exactly one
+// permission or service account.
+type NameLookupError struct {
+	// Kind is the kind of object looked up, PermissionKind or
+	// ServiceAccountKind.
+	Kind string
+	// Name is the name which was looked up.
+	Name string
+	// Ids lists the IDs of the objects with the name, when it is ambiguous.
+	Ids []string
+	// Err is ErrNameNotFound or ErrAmbiguousName.
+	Err error
+}
+
+func (e *NameLookupError) Error() string {
+	if errors.Is(e.Err, ErrAmbiguousName) {
+		return e.Kind + " name " + strconv.Quote(e.Name) +
+			" is ambiguous; matching IDs: " + strings.Join(e.Ids, ", ")
+	}
+	return "no " + e.Kind + " named " + strconv.Quote(e.Name)
+}
+
+func (e *NameLookupError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel for a missing object of the kind
+// looked up, ErrPermissionNotFound or ErrServiceAcctNotFound, when no object
+// has the name. This allows missing names and missing IDs to be handled
+// alike.
+func (e *NameLookupError) Is(target error) bool {
+	if e.Err != ErrNameNotFound {
+		return false
+	}
+
+	switch e.Kind {
+	case PermissionKind:
+		return target == ErrPermissionNotFound
+	case ServiceAccountKind:
+		return target == ErrServiceAcctNotFound
+	}
+	return false
+}
+
+// ByName returns the permission in the list with the given name. A
+// *NameLookupError is returned if no permission, or more than one, has the
+// name. Names are compared exactly.
+func (l PermissionList) ByName(name string) (*Permission, error) {
+	var found *Permission
+	var ids []string
+
+	for i := range l {
+		if l[i].Name == name {
+			found = &l[i]
+			ids = append(ids, l[i].Id)
+		}
+	}
+
+	if err := nameLookupErr(PermissionKind, name, ids); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// ByName returns the service account in the list with the given name. A
+// *NameLookupError is returned if no service account, or more than one, has
+// the name. Names are compared exactly.
+func (l ServiceAcctList) ByName(name string) (*ServiceAcct, error) {
+	var found *ServiceAcct
+	var ids []string
+
+	for i := range l {
+		if l[i].Name == name {
+			found = &l[i]
+			ids = append(ids, l[i].Id)
+		}
+	}
+
+	if err := nameLookupErr(ServiceAccountKind, name, ids); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// ResolveNames returns the IDs of the permissions with the given names, in
+// the same order. All names which do not identify exactly one permission are
+// reported together, with an error joining a *NameLookupError for each.
+func (l PermissionList) ResolveNames(names ...string) ([]string, error) {
+	ids := make([]string, 0, len(names))
+	var errs []error
+
+	for _, name := range names {
+		p, err := l.ByName(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ids = append(ids, p.Id)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return ids, nil
+}
+
+func nameLookupErr(kind, name string, ids []string) error {
+	switch len(ids) {
+	case 0:
+		return &NameLookupError{Kind: kind, Name: name, Err: ErrNameNotFound}
+	case 1:
+		return nil
+	}
+	return &NameLookupError{
+		Kind: kind, Name: name, Ids: ids, Err: ErrAmbiguousName}
+}
+
+// FindPermissionByName lists the permissions of the account and returns the
+// one with the given name. A *NameLookupError is returned if no permission,
+// or more than one, has the name. When no permission has the name, the error
+// also matches ErrPermissionNotFound.
+func (client *Client) FindPermissionByName(name string) (*Permission, error) {
+	perms, err := client.ListPermissions()
+	if err != nil {
+		return nil, err
+	}
+	return perms.ByName(name)
+}
+
+// FindServiceAccountByName lists the service accounts and returns the one with
+// the given name. A *NameLookupError is returned if no service account, or
+// more than one, has the name. When no service account has the name, the error
+// also matches ErrServiceAcctNotFound.
+func (client *Client) FindServiceAccountByName(
+	name string) (*ServiceAcct, error) {
+	accts, err := client.ListServiceAccounts()
+	if err != nil {
+		return nil, err
+	}
+	return accts.ByName(name)
+}
+
+// ResolvePermissionNames lists the permissions of the account and returns the
+// IDs of those with the given names, in the same order. This allows a
+// CreateServiceAcctReq to be built from permission names:
+//
+//	ids, err := client.ResolvePermissionNames("logs-read", "uploads-write")
+//	if err != nil {
+//		return err
+//	}
+//	req := &CreateServiceAcctReq{Name: "ingest", Permissions: ids}
+//
+// All names which do not identify exactly one permission are reported
+// together, with an error joining a *NameLookupError for each.
+func (client *Client) ResolvePermissionNames(
+	names ...string) ([]string, error) {
+	perms, err := client.ListPermissions()
+	if err != nil {
+		return nil, err
+	}
+	return perms.ResolveNames(names...)
+}
@@ -0,0 +1,65 @@
+package lyveapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFindByName(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/permissions":
+				_, _ = w.Write([]byte(`[
+					{"id": "p1", "name": "logs-read"},
+					{"id": "p2", "name": "uploads-write"},
+					{"id": "p3", "name": "dup"},
+					{"id": "p4", "name": "dup"}]`))
+			case "/service-accounts":
+				_, _ = w.Write([]byte(`[{"id": "s1", "name": "ingest"}]`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer srv.Close()
+
+	client := &Client{apiUrl: srv.URL}
+
+	p, err := client.FindPermissionByName("uploads-write")
+	if err != nil || p.Id != "p2" {
+		t.Errorf("Unexpected result: %+v, %v", p, err)
+	}
+
+	_, err = client.FindPermissionByName("dup")
+	var lookupErr *NameLookupError
+	if !errors.As(err, &lookupErr) || !errors.Is(err, ErrAmbiguousName) ||
+		!reflect.DeepEqual(lookupErr.Ids, []string{"p3", "p4"}) {
+		t.Errorf("Expected an ambiguous name error; got %v", err)
+	}
+
+	sa, err := client.FindServiceAccountByName("ingest")
+	if err != nil || sa.Id != "s1" {
+		t.Errorf("Unexpected result: %+v, %v", sa, err)
+	}
+
+	_, err = client.FindServiceAccountByName("egress")
+	if !errors.Is(err, ErrServiceAcctNotFound) || !IsNotFound(err) ||
+		errors.Is(err, ErrPermissionNotFound) {
+		t.Errorf("Expected a not found error; got %v", err)
+	}
+
+	ids, err := client.ResolvePermissionNames("uploads-write", "logs-read")
+	if err != nil || !reflect.DeepEqual(ids, []string{"p2", "p1"}) {
+		t.Errorf("Unexpected result: %v, %v", ids, err)
+	}
+
+	_, err = client.ResolvePermissionNames("logs-read", "missing", "dup")
+	if !errors.Is(err, ErrNameNotFound) || !errors.Is(err, ErrAmbiguousName) {
+		t.Errorf("Expected both lookup failures to be reported; got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package lyveapi
+
+import "strings"
+
+// ToPolicyDocument returns the policy document equivalent to the permission.
+// For permissions of type "policy" this is the decoded Policy field. For the
+// structured permission types the document contains a single Allow statement,
+// where the actions are derived from Actions:
+//
+//	read-only      -> s3:Get*, s3:List*
+//	write-only     -> s3:PutObject*, s3:DeleteObject*, s3:AbortMultipartUpload
+//	all-operations -> s3:*
+//
+// and the resources from the type:
+//
+//	all-buckets    -> *
+//	bucket-prefix  -> arn:aws:s3:::<prefix>*
+//	bucket-names   -> arn:aws:s3:::<bucket> and arn:aws:s3:::<bucket>/*
+func (p *Permission) ToPolicyDocument() (*PolicyDocument, error) {
+	return permissionPolicyDocument(p)
+}
+
+// ToPolicyPermission returns a copy of the permission converted to type
+// "policy", carrying the equivalent policy document. The identifier, name and
+// description are retained.
+func (p *Permission) ToPolicyPermission() (*Permission, error) {
+	doc, err := p.ToPolicyDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Permission{
+		Id:          p.Id,
+		Name:        p.Name,
+		Description: p.Description,
+		ReadyState:  p.ReadyState,
+		CreateTime:  p.CreateTime,
+	}
+
+	if err = out.SetPolicyDocument(doc); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Simplify returns a copy of a policy permission converted to the simplest
+// equivalent structured permission type, and true. If the permission is not a
+// policy permission, or its policy cannot be expressed as a structured
+// permission, the permission is returned unchanged along with false.
+func (p *Permission) Simplify() (*Permission, bool) {
+	if !p.IsPolicyPermission() {
+		return p, false
+	}
+
+	doc, err := p.PolicyDocument()
+	if err != nil {
+		return p, false
+	}
+
+	simple, ok := SimplifyPolicyDocument(doc)
+	if !ok {
+		return p, false
+	}
+
+	simple.Id = p.Id
+	simple.Name = p.Name
+	simple.Description = p.Description
+	simple.ReadyState = p.ReadyState
+	simple.CreateTime = p.CreateTime
+	return simple, true
+}
+
+// SimplifyPolicyDocument recognizes policy documents which are equivalent to a
+// structured permission, as produced by Permission.ToPolicyDocument, and
+// returns that permission, without a name, and true. Documents with more than
+// one statement, Deny statements, conditions, principals or NotAction and
+// NotResource elements are never considered simple, in which case nil and
+// false are returned.
+func SimplifyPolicyDocument(doc *PolicyDocument) (*Permission, bool) {
+	if len(doc.Statement) != 1 {
+		return nil, false
+	}
+
+	s := &doc.Statement[0]
+	if s.Effect != EffectAllow || len(s.Condition) > 0 ||
+		s.Principal != nil || s.NotPrincipal != nil ||
+		len(s.NotAction) > 0 || len(s.NotResource) > 0 ||
+		len(s.Action) == 0 || len(s.Resource) == 0 {
+		return nil, false
+	}
+
+	action, ok := structuredActionFor(s.Action)
+	if !ok {
+		return nil, false
+	}
+
+	perm := &Permission{Actions: action}
+	if !simplifyResources(perm, s.Resource) {
+		return nil, false
+	}
+	return perm, true
+}
+
+// structuredActionFor returns the structured action granting exactly the given
+// action patterns.
+func structuredActionFor(actions []string) (Action, bool) {
+	if len(actions) == 1 && actions[0] == "*" {
+		return AllOperations, true
+	}
+
+	got := lowerSet(actions)
+	for a, patterns := range structuredActions {
+		if setsEqual(got, lowerSet(patterns)) {
+			return a, true
+		}
+	}
+	return "", false
+}
+
+// simplifyResources sets the type and the type-specific fields of perm from
+// the resources of a simple statement, returning false if the resources do
+// not correspond to a structured permission type.
+func simplifyResources(perm *Permission, resources []string) bool {
+	if len(resources) == 1 {
+		r := resources[0]
+		// BucketARN("*") does not cover account-level actions, so only "*"
+		// is as broad as an all-buckets permission.
+		if r == "*" {
+			perm.Type = AllBuckets
+			return true
+		}
+
+		name := strings.TrimPrefix(r, s3ArnPrefix)
+		if strings.HasPrefix(r, s3ArnPrefix) && strings.HasSuffix(name, "*") {
+			prefix := strings.TrimSuffix(name, "*")
+			if bucketPrefixRe.MatchString(prefix) {
+				perm.Type = BucketPrefix
+				perm.Prefix = prefix
+				return true
+			}
+			return false
+		}
+	}
+
+	// Each bucket must be named both as a bucket and as the set of all of its
+	// objects, in either order.
+	buckets := map[string]uint8{}
+	var order []string
+	for _, r := range resources {
+		if !strings.HasPrefix(r, s3ArnPrefix) {
+			return false
+		}
+
+		name := strings.TrimPrefix(r, s3ArnPrefix)
+		var bit uint8 = 1
+		if strings.HasSuffix(name, "/*") {
+			name, bit = strings.TrimSuffix(name, "/*"), 2
+		}
+
+		if !bucketNameRe.MatchString(name) {
+			return false
+		}
+
+		if _, seen := buckets[name]; !seen {
+			order = append(order, name)
+		}
+		buckets[name] |= bit
+	}
+
+	for _, b := range order {
+		if buckets[b] != 3 {
+			return false
+		}
+	}
+
+	perm.Type = BucketNames
+	perm.Buckets = order
+	return true
+}
+
+// lowerSet returns the lowercase forms of values as a set.
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToLower(v)] = true
+	}
+	return set
+}
+
+func setsEqual(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+	return true
+}
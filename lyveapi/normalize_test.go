@@ -0,0 +1,66 @@
+package lyveapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPermissionSimplifyRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range []Permission{
+		{Name: "all", Type: AllBuckets, Actions: AllOperations},
+		{Name: "prefix", Type: BucketPrefix, Prefix: "logs-", Actions: ReadOnly},
+		{Name: "names", Type: BucketNames, Buckets: []string{"beta", "alpha"},
+			Actions: WriteOnly},
+	} {
+		policyPerm, err := p.ToPolicyPermission()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", p.Name, err)
+		}
+
+		if policyPerm.Type != Policy || policyPerm.Name != p.Name {
+			t.Errorf("%s: unexpected policy permission: %+v", p.Name, policyPerm)
+		}
+
+		simple, ok := policyPerm.Simplify()
+		if !ok {
+			t.Fatalf("%s: expected policy to simplify: %s", p.Name, policyPerm.Policy)
+		}
+
+		if !reflect.DeepEqual(*simple, p) {
+			t.Errorf("%s: expected %+v; got %+v", p.Name, p, *simple)
+		}
+	}
+}
+
+func TestSimplifyPolicyDocumentRejects(t *testing.T) {
+	t.Parallel()
+
+	for name, policy := range map[string]string{
+		"two-statements": `{"Statement":[
+			{"Effect":"Allow","Action":"s3:*","Resource":"arn:aws:s3:::a"},
+			{"Effect":"Allow","Action":"s3:*","Resource":"arn:aws:s3:::b"}]}`,
+		"deny": `{"Statement":[{"Effect":"Deny","Action":"s3:*",
+			"Resource":"arn:aws:s3:::*"}]}`,
+		"condition": `{"Statement":[{"Effect":"Allow","Action":"s3:*",
+			"Resource":"arn:aws:s3:::*","Condition":{"Bool":{"aws:SecureTransport":"true"}}}]}`,
+		"partial-actions": `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject",
+			"Resource":"arn:aws:s3:::*"}]}`,
+		"all-bucket-arns": `{"Statement":[{"Effect":"Allow","Action":"s3:*",
+			"Resource":"arn:aws:s3:::*"}]}`,
+		"objects-only": `{"Statement":[{"Effect":"Allow","Action":"s3:*",
+			"Resource":"arn:aws:s3:::alpha/*"}]}`,
+		"key-prefix": `{"Statement":[{"Effect":"Allow","Action":"s3:*",
+			"Resource":"arn:aws:s3:::alpha/logs*"}]}`,
+	} {
+		doc, err := ParsePolicyDocument(policy)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		if p, ok := SimplifyPolicyDocument(doc); ok {
+			t.Errorf("%s: expected no simplification; got %+v", name, p)
+		}
+	}
+}
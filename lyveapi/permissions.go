@@ -1,14 +1,28 @@
 package lyveapi
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 )
 
 // CreatePermission creates a new permission with the specified parameters.
-// A nil and an error are returned upon failure.
-func (client *Client) CreatePermission(createReq *Permission) (*Permission, error) {
+// A nil and an error are returned upon failure. Pass WaitUntilReady to block
+// until the new permission is ready.
+func (client *Client) CreatePermission(
+	createReq *Permission, opts ...CreateOption) (*Permission, error) {
+	return client.CreatePermissionWithContext(
+		context.Background(), createReq, opts...)
+}
+
+// CreatePermissionWithContext is functionally identical to
+// CreatePermission(...) with the only difference being the context parameter
+// as the first argument, which is passed to the underlying HTTP request.
+func (client *Client) CreatePermissionWithContext(ctx context.Context,
+	createReq *Permission, opts ...CreateOption) (*Permission, error) {
+	const op = "CreatePermission"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/permissions"
 	token := client.token
@@ -18,34 +32,46 @@ func (client *Client) CreatePermission(createReq *Permission) (*Permission, erro
 	var err error
 	var rdr io.ReadCloser
 
-	// If the permission type is "policy", we must have a policy object
-	// associated with the permission.
-	if createReq.IsPolicyPermission() && createReq.Policy == "" {
-		return nil, PolicyMissingErr
+	// Catch invalid combinations of fields before they reach the API. If the
+	// permission type is "policy", we must have a policy object associated
+	// with the permission, in which case the error wraps PolicyMissingErr.
+	// Fields which do not belong to the type are rejected too.
+	if err = createReq.validate(true); err != nil {
+		return nil, opError(op, http.MethodPost, endpoint, err)
 	}
 
 	if buf, err = json.Marshal(createReq); err != nil {
-		return nil, err
+		return nil, opError(op, http.MethodPost, endpoint, err)
 	}
 
-	if rdr, err = apiRequestAuthenticated(
-		token, http.MethodPost, endpoint, buf); err != nil {
+	if rdr, err = apiRequestAuthenticatedWithContext(ctx,
+		op, token, http.MethodPost, endpoint, buf); err != nil {
 		return nil, err
 	}
 
 	defer rdr.Close()
 
 	var permission = &Permission{}
-	respBodyDecoder := json.NewDecoder(rdr)
-	if err := respBodyDecoder.Decode(permission); err != nil {
+	if err := decodeResponseBody(rdr, permission); err != nil {
 		return nil, err
 	}
+
+	if o := newCreateOptions(opts); o.wait && !permission.ReadyState {
+		ready, err := client.waitPermissionReady(
+			o.waitCtx, permission.Id, o.waitOpts, true)
+		if err != nil {
+			return permission, opError(op, http.MethodPost, endpoint, err)
+		}
+		return ready, nil
+	}
 	return permission, nil
 }
 
 // ListPermissions produces a list of Permission structs that are part of this
 // account. A nil and an error are returned upon failure.
 func (client *Client) ListPermissions() (*PermissionList, error) {
+	const op = "ListPermissions"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/permissions"
 	token := client.token
@@ -55,15 +81,14 @@ func (client *Client) ListPermissions() (*PermissionList, error) {
 	var rdr io.ReadCloser
 
 	if rdr, err = apiRequestAuthenticated(
-		token, http.MethodGet, endpoint, nil); err != nil {
+		op, token, http.MethodGet, endpoint, nil); err != nil {
 		return nil, err
 	}
 
 	defer rdr.Close()
 
 	var permsList = &PermissionList{}
-	respBodyDecoder := json.NewDecoder(rdr)
-	if err := respBodyDecoder.Decode(permsList); err != nil {
+	if err := decodeResponseBody(rdr, permsList); err != nil {
 		return nil, err
 	}
 	return permsList, nil
@@ -73,6 +98,16 @@ func (client *Client) ListPermissions() (*PermissionList, error) {
 // permission id if one was found. If a permission for the specified id is not
 // found An nil and an error will be returned.
 func (client *Client) GetPermission(permissionId string) (*Permission, error) {
+	return client.GetPermissionWithContext(context.Background(), permissionId)
+}
+
+// GetPermissionWithContext is functionally identical to GetPermission(...)
+// with the only difference being the context parameter as the first argument,
+// which is passed to the underlying HTTP request.
+func (client *Client) GetPermissionWithContext(
+	ctx context.Context, permissionId string) (*Permission, error) {
+	const op = "GetPermission"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/permissions"
 	url := endpoint + "/" + permissionId
@@ -82,16 +117,15 @@ func (client *Client) GetPermission(permissionId string) (*Permission, error) {
 	var err error
 	var rdr io.ReadCloser
 
-	if rdr, err = apiRequestAuthenticated(
-		token, http.MethodGet, url, nil); err != nil {
+	if rdr, err = apiRequestAuthenticatedWithContext(ctx,
+		op, token, http.MethodGet, url, nil); err != nil {
 		return nil, err
 	}
 
 	defer rdr.Close()
 
 	var permission = &Permission{}
-	respBodyDecoder := json.NewDecoder(rdr)
-	if err := respBodyDecoder.Decode(permission); err != nil {
+	if err := decodeResponseBody(rdr, permission); err != nil {
 		return nil, err
 	}
 	return permission, nil
@@ -102,6 +136,8 @@ func (client *Client) GetPermission(permissionId string) (*Permission, error) {
 // Id. A successful request will return a nil, whereas an error is
 // returned if no such permission could be found.
 func (client *Client) DeletePermission(permissionId string) error {
+	const op = "DeletePermission"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/permissions"
 	url := endpoint + "/" + permissionId
@@ -111,7 +147,8 @@ func (client *Client) DeletePermission(permissionId string) error {
 	var err error
 	var rdr io.ReadCloser
 
-	if rdr, err = apiRequestAuthenticated(token, http.MethodDelete, url, nil); err != nil {
+	if rdr, err = apiRequestAuthenticated(
+		op, token, http.MethodDelete, url, nil); err != nil {
 		return err
 	}
 
@@ -127,9 +164,12 @@ func (client *Client) DeletePermission(permissionId string) error {
 
 // UpdatePermission updates a permission associated with the given permission
 // Id. A successful request will return a nil, whereas an error is returned if
-// the request failed.
-func (client *Client) UpdatePermission(
-	permissionId string, updateReq *Permission) error {
+// the request failed. With the IfUnchanged option, the update is only written
+// if the permission has not changed since it was fetched.
+func (client *Client) UpdatePermission(permissionId string,
+	updateReq *Permission, opts ...UpdateOption) error {
+	const op = "UpdatePermission"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/permissions"
 	url := endpoint + "/" + permissionId
@@ -140,12 +180,21 @@ func (client *Client) UpdatePermission(
 	var err error
 	var rdr io.ReadCloser
 
-	if buf, err = json.Marshal(updateReq); err != nil {
+	if err = updateReq.Validate(); err != nil {
+		return opError(op, http.MethodPut, url, err)
+	}
+
+	if err = client.checkPermissionUnchanged(
+		op, url, permissionId, newUpdateOptions(opts)); err != nil {
 		return err
 	}
 
+	if buf, err = json.Marshal(updateReq); err != nil {
+		return opError(op, http.MethodPut, url, err)
+	}
+
 	if rdr, err = apiRequestAuthenticated(
-		token, http.MethodPut, url, buf); err != nil {
+		op, token, http.MethodPut, url, buf); err != nil {
 		return err
 	}
 
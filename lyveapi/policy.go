@@ -0,0 +1,476 @@
+package lyveapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PolicyVersion is the current version of the policy language, which should be
+// used for all new policy documents.
+const PolicyVersion = "2012-10-17"
+
+// s3ArnPrefix is the prefix of ARNs identifying S3 buckets and objects.
+const s3ArnPrefix = "arn:aws:s3:::"
+
+// ErrNotPolicyPermission is returned when a policy document is requested from
+// or set on a permission whose type is not "policy".
+var ErrNotPolicyPermission = errors.New("permission is not a policy permission")
+
+// Effect determines whether a policy statement allows or denies access.
+type Effect string
+
+const (
+	EffectAllow Effect = "Allow"
+	EffectDeny  Effect = "Deny"
+)
+
+// StringList is a list of strings in a policy document. The policy language
+// allows such fields to be either a single string or an array of strings,
+// both of which are accepted when unmarshaling. A list with a single element
+// is marshaled as a string.
+type StringList []string
+
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+	return json.Marshal([]string(l))
+}
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*l = nil
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '[' {
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		list := make(StringList, 0, len(raw))
+		for _, r := range raw {
+			s, err := scalarString(r)
+			if err != nil {
+				return err
+			}
+			list = append(list, s)
+		}
+		*l = list
+		return nil
+	}
+
+	s, err := scalarString(data)
+	if err != nil {
+		return err
+	}
+	*l = StringList{s}
+	return nil
+}
+
+// Contains returns true if s is an element of the list.
+func (l StringList) Contains(s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// scalarString converts a JSON string, number or boolean to a string.
+// Condition values in particular are often written as bare booleans or
+// numbers.
+func scalarString(data json.RawMessage) (string, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return "", errors.New("expected a string or an array of strings")
+	}
+
+	switch data[0] {
+	case '"':
+		var s string
+		err := json.Unmarshal(data, &s)
+		return s, err
+	case 't', 'f':
+		b, err := strconv.ParseBool(string(data))
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	case '{', '[', 'n':
+		return "", fmt.Errorf(
+			"expected a string or an array of strings; got %s", data)
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return "", err
+	}
+	return n.String(), nil
+}
+
+// Principal identifies who a statement applies to. In a policy document it is
+// either the wildcard "*" or an object mapping a principal type, such as "AWS",
+// to one or more identifiers.
+type Principal struct {
+	// Wildcard is true when the principal is "*", meaning everyone.
+	Wildcard bool
+	// Values maps principal types to identifiers.
+	Values map[string]StringList
+}
+
+// WildcardPrincipal returns a principal matching everyone.
+func WildcardPrincipal() *Principal {
+	return &Principal{Wildcard: true}
+}
+
+func (p Principal) MarshalJSON() ([]byte, error) {
+	if p.Wildcard {
+		return json.Marshal("*")
+	}
+	return json.Marshal(p.Values)
+}
+
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if s != "*" {
+			return fmt.Errorf("principal must be \"*\" or an object; got %q", s)
+		}
+		*p = Principal{Wildcard: true}
+		return nil
+	}
+
+	var values map[string]StringList
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*p = Principal{Values: values}
+	return nil
+}
+
+// Condition maps condition operators, such as "StringLike", to condition keys,
+// such as "s3:prefix", and the values they are compared with.
+type Condition map[string]map[string]StringList
+
+// Statement is a single statement of a policy document.
+type Statement struct {
+	Sid          string     `json:"Sid,omitempty"`
+	Effect       Effect     `json:"Effect"`
+	Principal    *Principal `json:"Principal,omitempty"`
+	NotPrincipal *Principal `json:"NotPrincipal,omitempty"`
+	Action       StringList `json:"Action,omitempty"`
+	NotAction    StringList `json:"NotAction,omitempty"`
+	Resource     StringList `json:"Resource,omitempty"`
+	NotResource  StringList `json:"NotResource,omitempty"`
+	Condition    Condition  `json:"Condition,omitempty"`
+}
+
+// PolicyDocument is a typed representation of the JSON policy document
+// carried in the Policy field of a permission of type "policy". The document
+// follows the IAM policy language.
+type PolicyDocument struct {
+	Version   string      `json:"Version,omitempty"`
+	Id        string      `json:"Id,omitempty"`
+	Statement []Statement `json:"Statement"`
+}
+
+func (d *PolicyDocument) UnmarshalJSON(data []byte) error {
+	// Statement may be a single object instead of an array of objects.
+	var raw struct {
+		Version   string          `json:"Version"`
+		Id        string          `json:"Id"`
+		Statement json.RawMessage `json:"Statement"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	doc := PolicyDocument{Version: raw.Version, Id: raw.Id}
+	stmts := bytes.TrimSpace(raw.Statement)
+	switch {
+	case len(stmts) == 0 || bytes.Equal(stmts, []byte("null")):
+	case stmts[0] == '{':
+		var s Statement
+		if err := json.Unmarshal(stmts, &s); err != nil {
+			return err
+		}
+		doc.Statement = []Statement{s}
+	default:
+		if err := json.Unmarshal(stmts, &doc.Statement); err != nil {
+			return err
+		}
+	}
+
+	*d = doc
+	return nil
+}
+
+// ParsePolicyDocument decodes a JSON policy document, such as the value of the
+// Policy field of a permission.
+func ParsePolicyDocument(policy string) (*PolicyDocument, error) {
+	doc := &PolicyDocument{}
+	if err := json.Unmarshal([]byte(policy), doc); err != nil {
+		return nil, fmt.Errorf("invalid policy document: %w", err)
+	}
+	return doc, nil
+}
+
+// JSON returns the document serialized as compact JSON.
+func (d *PolicyDocument) JSON() (string, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Validate checks the structure of the document. Each statement must have an
+// effect, either actions or excluded actions, and either resources or excluded
+// resources. A *ValidationError describing all problems found is returned, or
+// nil if there are none.
+func (d *PolicyDocument) Validate() error {
+	v := &validator{object: "PolicyDocument"}
+
+	if d.Version != "" && d.Version != PolicyVersion &&
+		d.Version != "2008-10-17" {
+		v.add("Version", "unknown policy language version "+d.Version)
+	}
+
+	if len(d.Statement) == 0 {
+		v.add("Statement", "at least one statement is required")
+	}
+
+	for i := range d.Statement {
+		d.Statement[i].validate(v, fmt.Sprintf("Statement[%d]", i))
+	}
+
+	return v.err()
+}
+
+func (s *Statement) validate(v *validator, field string) {
+	switch s.Effect {
+	case EffectAllow, EffectDeny:
+	case "":
+		v.add(field+".Effect", "is required")
+	default:
+		v.add(field+".Effect", "must be Allow or Deny; got "+string(s.Effect))
+	}
+
+	switch {
+	case len(s.Action) == 0 && len(s.NotAction) == 0:
+		v.add(field+".Action", "either Action or NotAction is required")
+	case len(s.Action) > 0 && len(s.NotAction) > 0:
+		v.add(field+".Action", "Action and NotAction are mutually exclusive")
+	}
+
+	switch {
+	case len(s.Resource) == 0 && len(s.NotResource) == 0:
+		v.add(field+".Resource", "either Resource or NotResource is required")
+	case len(s.Resource) > 0 && len(s.NotResource) > 0:
+		v.add(field+".Resource",
+			"Resource and NotResource are mutually exclusive")
+	}
+
+	if s.Principal != nil && s.NotPrincipal != nil {
+		v.add(field+".Principal",
+			"Principal and NotPrincipal are mutually exclusive")
+	}
+}
+
+// BucketARN returns the ARN identifying the named bucket in a policy document.
+func BucketARN(bucket string) string {
+	return s3ArnPrefix + bucket
+}
+
+// ObjectARN returns the ARN identifying objects in the named bucket whose keys
+// match keyPattern, which may contain wildcards, for example "reports/*".
+func ObjectARN(bucket, keyPattern string) string {
+	return s3ArnPrefix + bucket + "/" + keyPattern
+}
+
+// PolicyDocument decodes the Policy field of a permission of type "policy". An
+// error wrapping ErrNotPolicyPermission is returned for other permission types.
+func (p *Permission) PolicyDocument() (*PolicyDocument, error) {
+	if !p.IsPolicyPermission() {
+		return nil, fmt.Errorf("%w: type is %s",
+			ErrNotPolicyPermission, p.Type)
+	}
+
+	if strings.TrimSpace(p.Policy) == "" {
+		return nil, PolicyMissingErr
+	}
+
+	return ParsePolicyDocument(p.Policy)
+}
+
+// SetPolicyDocument serializes doc into the Policy field and changes the type
+// of the permission to "policy". Fields which only apply to other permission
+// types are cleared.
+func (p *Permission) SetPolicyDocument(doc *PolicyDocument) error {
+	if doc == nil {
+		return PolicyMissingErr
+	}
+
+	policy, err := doc.JSON()
+	if err != nil {
+		return err
+	}
+
+	p.Type = Policy
+	p.Policy = policy
+	p.Actions = ""
+	p.Prefix = ""
+	p.Buckets = nil
+	return nil
+}
+
+// PolicyBuilder constructs policy documents with a fluent interface. Allow and
+// Deny each begin a new statement, while the remaining methods modify the most
+// recently begun statement, for example:
+//
+//	doc, err := NewPolicyBuilder().
+//		Allow("s3:GetObject", "s3:ListBucket").
+//		On(BucketARN("reports"), ObjectARN("reports", "*")).
+//		Deny("s3:DeleteObject").
+//		On(ObjectARN("reports", "*")).
+//		Build()
+type PolicyBuilder struct {
+	doc PolicyDocument
+	err error
+}
+
+// NewPolicyBuilder returns a builder for a document using the current
+// version of the policy language.
+func NewPolicyBuilder() *PolicyBuilder {
+	return &PolicyBuilder{doc: PolicyDocument{Version: PolicyVersion}}
+}
+
+// Id sets the optional identifier of the document.
+func (b *PolicyBuilder) Id(id string) *PolicyBuilder {
+	b.doc.Id = id
+	return b
+}
+
+// Allow begins a new statement allowing the given actions.
+func (b *PolicyBuilder) Allow(actions ...string) *PolicyBuilder {
+	return b.begin(EffectAllow, actions)
+}
+
+// Deny begins a new statement denying the given actions.
+func (b *PolicyBuilder) Deny(actions ...string) *PolicyBuilder {
+	return b.begin(EffectDeny, actions)
+}
+
+func (b *PolicyBuilder) begin(effect Effect, actions []string) *PolicyBuilder {
+	b.doc.Statement = append(b.doc.Statement, Statement{
+		Effect: effect,
+		Action: append(StringList(nil), actions...),
+	})
+	return b
+}
+
+// current returns the most recently begun statement, or nil after recording
+// an error when no statement has been begun.
+func (b *PolicyBuilder) current(method string) *Statement {
+	if len(b.doc.Statement) == 0 {
+		if b.err == nil {
+			b.err = errors.New(
+				"policy builder: " + method + " called before Allow or Deny")
+		}
+		return nil
+	}
+	return &b.doc.Statement[len(b.doc.Statement)-1]
+}
+
+// Sid sets the identifier of the current statement.
+func (b *PolicyBuilder) Sid(sid string) *PolicyBuilder {
+	if s := b.current("Sid"); s != nil {
+		s.Sid = sid
+	}
+	return b
+}
+
+// Except turns the current statement into one which applies to all actions
+// other than the given ones, using NotAction. Actions passed to Allow or Deny
+// are discarded.
+func (b *PolicyBuilder) Except(actions ...string) *PolicyBuilder {
+	if s := b.current("Except"); s != nil {
+		s.Action = nil
+		s.NotAction = append(s.NotAction, actions...)
+	}
+	return b
+}
+
+// On adds resources to the current statement.
+func (b *PolicyBuilder) On(resources ...string) *PolicyBuilder {
+	if s := b.current("On"); s != nil {
+		s.Resource = append(s.Resource, resources...)
+	}
+	return b
+}
+
+// NotOn adds excluded resources to the current statement, using NotResource.
+func (b *PolicyBuilder) NotOn(resources ...string) *PolicyBuilder {
+	if s := b.current("NotOn"); s != nil {
+		s.NotResource = append(s.NotResource, resources...)
+	}
+	return b
+}
+
+// When adds a condition to the current statement, for example
+// When("IpAddress", "aws:SourceIp", "203.0.113.0/24").
+func (b *PolicyBuilder) When(
+	operator, key string, values ...string) *PolicyBuilder {
+	if s := b.current("When"); s != nil {
+		if s.Condition == nil {
+			s.Condition = Condition{}
+		}
+		if s.Condition[operator] == nil {
+			s.Condition[operator] = map[string]StringList{}
+		}
+		s.Condition[operator][key] = append(
+			s.Condition[operator][key], values...)
+	}
+	return b
+}
+
+// Principal sets the principal of the current statement.
+func (b *PolicyBuilder) Principal(p *Principal) *PolicyBuilder {
+	if s := b.current("Principal"); s != nil {
+		s.Principal = p
+	}
+	return b
+}
+
+// Build returns the constructed document after validating it.
+func (b *PolicyBuilder) Build() (*PolicyDocument, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	doc := b.doc
+	doc.Statement = append([]Statement(nil), b.doc.Statement...)
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// sortedKeys returns the keys of m in lexical order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,120 @@
+package lyveapi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePolicyDocument(t *testing.T) {
+	t.Parallel()
+
+	const policy = `{
+		"Version": "2012-10-17",
+		"Statement": {
+			"Sid": "single",
+			"Effect": "Allow",
+			"Principal": "*",
+			"Action": "s3:GetObject",
+			"Resource": ["arn:aws:s3:::alpha/*", "arn:aws:s3:::beta/*"],
+			"Condition": {"Bool": {"aws:SecureTransport": true}}
+		}
+	}`
+
+	doc, err := ParsePolicyDocument(policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(doc.Statement) != 1 {
+		t.Fatalf("Expected a single statement; got %d", len(doc.Statement))
+	}
+
+	s := doc.Statement[0]
+	if !reflect.DeepEqual(s.Action, StringList{"s3:GetObject"}) {
+		t.Errorf("Unexpected actions: %v", s.Action)
+	}
+
+	if len(s.Resource) != 2 {
+		t.Errorf("Unexpected resources: %v", s.Resource)
+	}
+
+	if s.Principal == nil || !s.Principal.Wildcard {
+		t.Errorf("Expected wildcard principal; got %+v", s.Principal)
+	}
+
+	if v := s.Condition["Bool"]["aws:SecureTransport"]; !v.Contains("true") {
+		t.Errorf("Unexpected condition values: %v", v)
+	}
+
+	out, err := doc.JSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	again, err := ParsePolicyDocument(out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(doc, again) {
+		t.Errorf("Round trip mismatch: %+v != %+v", doc, again)
+	}
+}
+
+func TestPolicyBuilder(t *testing.T) {
+	t.Parallel()
+
+	doc, err := NewPolicyBuilder().
+		Allow("s3:GetObject", "s3:ListBucket").Sid("read").
+		On(BucketARN("reports"), ObjectARN("reports", "*")).
+		Deny().Except("s3:GetObject").
+		On(ObjectARN("reports", "*")).
+		When("NotIpAddress", "aws:SourceIp", "203.0.113.0/24").
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if doc.Version != PolicyVersion || len(doc.Statement) != 2 {
+		t.Fatalf("Unexpected document: %+v", doc)
+	}
+
+	if doc.Statement[1].NotAction[0] != "s3:GetObject" ||
+		doc.Statement[1].Action != nil {
+		t.Errorf("Unexpected statement: %+v", doc.Statement[1])
+	}
+
+	if _, err = NewPolicyBuilder().On("arn:aws:s3:::x").Build(); err == nil {
+		t.Error("Expected an error when On is called before Allow or Deny")
+	}
+
+	if _, err = NewPolicyBuilder().Allow("s3:GetObject").Build(); !errors.Is(
+		err, ErrValidationFailed) {
+		t.Errorf("Expected a validation error; got %v", err)
+	}
+}
+
+func TestPermissionPolicyDocument(t *testing.T) {
+	t.Parallel()
+
+	doc, _ := NewPolicyBuilder().Allow("s3:*").On("arn:aws:s3:::*").Build()
+	p := &Permission{Name: "a", Type: BucketNames, Buckets: []string{"x"}}
+
+	if _, err := p.PolicyDocument(); !errors.Is(err, ErrNotPolicyPermission) {
+		t.Errorf("Expected ErrNotPolicyPermission; got %v", err)
+	}
+
+	if err := p.SetPolicyDocument(doc); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if p.Type != Policy || p.Buckets != nil {
+		t.Errorf("Unexpected permission: %+v", p)
+	}
+
+	got, err := p.PolicyDocument()
+	if err != nil || !reflect.DeepEqual(got, doc) {
+		t.Errorf("Expected %+v; got %+v (%v)", doc, got, err)
+	}
+}
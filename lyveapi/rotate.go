@@ -0,0 +1,319 @@
+package lyveapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CredentialSink receives the credentials of a newly created service account,
+// which the API returns only once, and stores them where workloads will pick
+// them up. AWSCredentialsSink, RcloneSink, S3cmdSink, DotenvSink and
+// KubernetesSecretSink write common configuration files.
+type CredentialSink interface {
+	// StoreCredentials stores the credentials of the service account with
+	// the given name.
+	StoreCredentials(ctx context.Context, name string,
+		creds *CreateServiceAcctResp) error
+}
+
+// CredentialSinkFunc adapts a function to a CredentialSink.
+type CredentialSinkFunc func(ctx context.Context, name string,
+	creds *CreateServiceAcctResp) error
+
+// StoreCredentials calls f.
+func (f CredentialSinkFunc) StoreCredentials(ctx context.Context, name string,
+	creds *CreateServiceAcctResp) error {
+	return f(ctx, name, creds)
+}
+
+// ErrRotationIncomplete is matched by errors.Is when a rotation stopped
+// before it completed, and may be resumed with ResumeRotation.
+var ErrRotationIncomplete = errors.New("service account rotation incomplete")
+
+// RotationPhase is the progress of a credential rotation.
+type RotationPhase string
+
+const (
+	// RotationPending means the new service account has not been created.
+	RotationPending RotationPhase = "pending"
+	// RotationCreated means the new service account was created, but its
+	// credentials were not yet stored.
+	RotationCreated RotationPhase = "created"
+	// RotationStored means the credentials of the new service account were
+	// stored, but the old account was not yet disabled.
+	RotationStored RotationPhase = "stored"
+	// RotationOldDisabled means the old service account was disabled and
+	// will be deleted once the grace period has elapsed.
+	RotationOldDisabled RotationPhase = "old-disabled"
+	// RotationCompleted means the old service account was deleted.
+	RotationCompleted RotationPhase = "completed"
+)
+
+// RotationState records the progress of a credential rotation, so that it can
+// be saved and the rotation resumed if it is interrupted. It never holds the
+// new secret.
+type RotationState struct {
+	// OldId is the ID of the service account being replaced.
+	OldId string `json:"oldId"`
+	// NewName is the name of the replacement service account.
+	NewName string `json:"newName,omitempty"`
+	// NewId is the ID of the replacement service account, once created.
+	NewId string `json:"newId,omitempty"`
+	// Phase is the progress of the rotation.
+	Phase RotationPhase `json:"phase"`
+	// Creating is set while the new service account is being created. An
+	// account named NewName found when resuming in this state was created by
+	// the rotation before it was interrupted.
+	Creating bool `json:"creating,omitempty"`
+	// DisabledAt is when the old service account was disabled, from which
+	// the grace period is measured.
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+}
+
+// RotationOptions controls RotateServiceAccount and ResumeRotation.
+type RotationOptions struct {
+	// Sink receives the credentials of the new service account. It is
+	// required.
+	Sink CredentialSink
+	// NewName is the name of the new service account. If empty, the name of
+	// the old account is used with a UTC timestamp appended.
+	NewName string
+	// ExpirationDate, if set, is the expiration date of the new service
+	// account, in place of that of the old account. It must be set to rotate
+	// an account which has already expired.
+	ExpirationDate Timestamp
+	// Verify is called once the credentials are stored, and should check
+	// that workloads are using them. The old service account is only
+	// disabled when it returns nil. If nil, no verification is done.
+	Verify func(ctx context.Context, state *RotationState) error
+	// GracePeriod is the time between disabling the old service account and
+	// deleting it, during which it can be re-enabled if something still
+	// depends on it.
+	GracePeriod time.Duration
+	// SaveState, if set, is called whenever the phase changes, so the state
+	// can be persisted and the rotation resumed if the process is
+	// interrupted. An error from it stops the rotation.
+	SaveState func(state *RotationState) error
+}
+
+// RotationError is returned when a rotation fails. It matches
+// ErrRotationIncomplete, and the rotation may be resumed from State once the
+// cause has been dealt with.
+type RotationError struct {
+	// State is the state of the rotation when it stopped.
+	State RotationState
+	// Err is the cause of the failure.
+	Err error
+}
+
+func (e *RotationError) Error() string {
+	return "rotation of service account " + e.State.OldId + " stopped in " +
+		string(e.State.Phase) + " phase: " + e.Err.Error()
+}
+
+func (e *RotationError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrRotationIncomplete.
+func (e *RotationError) Is(target error) bool {
+	return target == ErrRotationIncomplete
+}
+
+// RotateServiceAccount replaces the service account with the given ID by a
+// new account with the same description, permissions and expiration date, so
+// that its credentials can be rotated. An account which has expired can only
+// be rotated with RotationOptions.ExpirationDate set. The rotation proceeds in phases:
+//
+//  1. the new service account is created;
+//  2. its credentials are handed to opts.Sink;
+//  3. opts.Verify is called, and the old account is disabled;
+//  4. the old account is deleted once opts.GracePeriod has elapsed.
+//
+// The rotation stops when ctx is done, which interrupts requests in flight and
+// waiting for the grace period, in which case a *RotationError is returned
+// and the rotation may be finished later with ResumeRotation. If the name of
+// the new account is already taken, the rotation fails without changing
+// anything. The final state is returned on success.
+func (client *Client) RotateServiceAccount(ctx context.Context,
+	svcAcctId string, opts *RotationOptions) (*RotationState, error) {
+	return client.ResumeRotation(ctx,
+		&RotationState{OldId: svcAcctId, Phase: RotationPending}, opts)
+}
+
+// ResumeRotation continues the rotation described by state, as saved by
+// RotationOptions.SaveState or returned in a *RotationError, as described by
+// RotateServiceAccount. state is updated as the rotation proceeds.
+//
+// Since the new secret is never saved, a rotation interrupted after the new
+// account was created but before its credentials were stored cannot recover
+// them; the new account, identified by the ID recorded in state, is deleted
+// and created again. The same applies to an account which the rotation was
+// creating when it was interrupted, which is found by its name. Accounts the
+// rotation did not record creating are never deleted.
+func (client *Client) ResumeRotation(ctx context.Context,
+	state *RotationState, opts *RotationOptions) (*RotationState, error) {
+	if opts == nil || opts.Sink == nil {
+		return state, &RotationError{State: *state,
+			Err: errors.New("no credential sink provided")}
+	}
+
+	for state.Phase != RotationCompleted {
+		if err := client.rotationStep(ctx, state, opts); err != nil {
+			return state, &RotationError{State: *state, Err: err}
+		}
+	}
+	return state, nil
+}
+
+// rotationStep advances the rotation by a single phase.
+func (client *Client) rotationStep(ctx context.Context,
+	state *RotationState, opts *RotationOptions) error {
+	save := func(phase RotationPhase) error {
+		state.Phase = phase
+		if opts.SaveState == nil {
+			return nil
+		}
+		return opts.SaveState(state)
+	}
+
+	switch state.Phase {
+	case RotationPending:
+		return client.rotationCreate(ctx, state, opts, save)
+
+	case RotationCreated:
+		// The credentials were lost when the rotation was interrupted.
+		if err := client.DeleteServiceAccountWithContext(
+			ctx, state.NewId); err != nil &&
+			!IsNotFound(err) {
+			return err
+		}
+		state.NewId = ""
+		return save(RotationPending)
+
+	case RotationStored:
+		if opts.Verify != nil {
+			if err := opts.Verify(ctx, state); err != nil {
+				return fmt.Errorf("verification failed: %w", err)
+			}
+		}
+		if err := client.DisableServiceAccountWithContext(
+			ctx, state.OldId); err != nil {
+			return err
+		}
+		disabledAt := time.Now().UTC()
+		state.DisabledAt = &disabledAt
+		return save(RotationOldDisabled)
+
+	case RotationOldDisabled:
+		// Without a record of when the old account was disabled, the grace
+		// period starts now rather than being skipped.
+		if state.DisabledAt == nil {
+			disabledAt := time.Now().UTC()
+			state.DisabledAt = &disabledAt
+			if err := save(RotationOldDisabled); err != nil {
+				return err
+			}
+		}
+		wait := time.Until(state.DisabledAt.Add(opts.GracePeriod))
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+		if err := client.DeleteServiceAccountWithContext(
+			ctx, state.OldId); err != nil &&
+			!IsNotFound(err) {
+			return err
+		}
+		return save(RotationCompleted)
+
+	default:
+		return fmt.Errorf("unknown rotation phase %q", state.Phase)
+	}
+}
+
+// rotationCreate creates the new service account and stores its credentials.
+func (client *Client) rotationCreate(ctx context.Context,
+	state *RotationState, opts *RotationOptions,
+	save func(RotationPhase) error) error {
+	old, err := client.GetServiceAccountWithContext(ctx, state.OldId)
+	if err != nil {
+		return err
+	}
+
+	if state.NewName == "" {
+		state.NewName = opts.NewName
+		if state.NewName == "" {
+			state.NewName = old.Name + "-" +
+				time.Now().UTC().Format("20060102T150405Z")
+		}
+	}
+
+	// The rotation was interrupted while creating the new account. If the
+	// account exists, its credentials were lost, so it is recorded and then
+	// replaced as in the created phase.
+	if state.Creating {
+		accts, err := client.ListServiceAccountsWithContext(ctx)
+		if err != nil {
+			return err
+		}
+		created, err := accts.ByName(state.NewName)
+		switch {
+		case err == nil && created.Id != state.OldId:
+			state.NewId, state.Creating = created.Id, false
+			return save(RotationCreated)
+		case err != nil && !errors.Is(err, ErrNameNotFound):
+			return err
+		}
+	}
+
+	expires := old.ExpirationDate
+	if opts.ExpirationDate != "" {
+		expires = opts.ExpirationDate
+	}
+	if t, err := expires.Time(); err == nil && !t.IsZero() &&
+		!t.After(time.Now()) {
+		return fmt.Errorf("service account %s expired at %s; set "+
+			"RotationOptions.ExpirationDate to rotate it", old.Id, expires)
+	}
+
+	// The name is saved along with Creating, so that the account can be found
+	// if the rotation is interrupted.
+	state.Creating = true
+	if err = save(RotationPending); err != nil {
+		return err
+	}
+
+	// A name which is already taken fails the creation, so an account the
+	// rotation did not create is never touched. Other failures may leave
+	// the account created, so Creating stays set.
+	creds, err := client.CreateServiceAccountWithContext(ctx,
+		&CreateServiceAcctReq{
+			Name:           state.NewName,
+			Description:    old.Description,
+			Permissions:    old.Permissions,
+			ExpirationDate: expires,
+		})
+	if IsConflict(err) {
+		state.Creating = false
+		if saveErr := save(RotationPending); saveErr != nil {
+			return errors.Join(err, saveErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Record the new account at once, so that an interrupted rotation only
+	// ever deletes the account it created.
+	state.NewId, state.Creating = creds.Id, false
+	if err = save(RotationCreated); err != nil {
+		return err
+	}
+
+	if err = opts.Sink.StoreCredentials(ctx, state.NewName, creds); err != nil {
+		return fmt.Errorf("storing credentials: %w", err)
+	}
+	return save(RotationStored)
+}
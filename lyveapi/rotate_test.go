@@ -0,0 +1,314 @@
+package lyveapi
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRotateServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+	old := f.addServiceAccount(ServiceAcct{
+		Name: "app", Description: "app", Enabled: true, Permissions: []string{p}})
+
+	var stored *CreateServiceAcctResp
+	var phases []RotationPhase
+	state, err := client.RotateServiceAccount(context.Background(), old,
+		&RotationOptions{
+			NewName: "app-2",
+			Sink: CredentialSinkFunc(func(_ context.Context, name string,
+				creds *CreateServiceAcctResp) error {
+				stored = creds
+				return nil
+			}),
+			Verify: func(_ context.Context, s *RotationState) error {
+				if stored == nil || stored.Id != s.NewId {
+					return errors.New("credentials not stored")
+				}
+				return nil
+			},
+			SaveState: func(s *RotationState) error {
+				phases = append(phases, s.Phase)
+				return nil
+			},
+		})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []RotationPhase{RotationPending, RotationCreated,
+		RotationStored, RotationOldDisabled, RotationCompleted}
+	if !reflect.DeepEqual(phases, expected) {
+		t.Errorf("Unexpected phases: %v", phases)
+	}
+
+	acct := f.serviceAccount(state.NewId)
+	if acct == nil || acct.Name != "app-2" || acct.Description != "app" ||
+		!reflect.DeepEqual(acct.Permissions, []string{p}) {
+		t.Errorf("Unexpected new service account: %+v", acct)
+	}
+	if f.serviceAccount(old) != nil {
+		t.Error("Expected the old service account to be deleted")
+	}
+}
+
+func TestRotationVerifyFailure(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+	old := f.addServiceAccount(ServiceAcct{
+		Name: "app", Enabled: true, Permissions: []string{p}})
+
+	sink := CredentialSinkFunc(func(context.Context, string,
+		*CreateServiceAcctResp) error {
+		return nil
+	})
+	opts := &RotationOptions{
+		Sink: sink,
+		Verify: func(context.Context, *RotationState) error {
+			return errors.New("workload still uses old key")
+		},
+	}
+
+	state, err := client.RotateServiceAccount(context.Background(), old, opts)
+	var rotErr *RotationError
+	if !errors.As(err, &rotErr) || !errors.Is(err, ErrRotationIncomplete) ||
+		state.Phase != RotationStored {
+		t.Fatalf("Expected the rotation to stop before disabling; got %v", err)
+	}
+	if !f.serviceAccount(old).Enabled {
+		t.Error("Expected the old service account to remain enabled")
+	}
+
+	opts.Verify = nil
+	if _, err = client.ResumeRotation(context.Background(), state, opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if f.serviceAccount(old) != nil || f.serviceAccount(state.NewId) == nil {
+		t.Error("Expected the rotation to complete")
+	}
+}
+
+func TestRotationResume(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+	old := f.addServiceAccount(ServiceAcct{
+		Name: "app", Enabled: true, Permissions: []string{p}})
+
+	// Interrupt the rotation after the new account was created, losing its
+	// credentials.
+	var saved RotationState
+	opts := &RotationOptions{
+		NewName: "app-2",
+		Sink: CredentialSinkFunc(func(context.Context, string,
+			*CreateServiceAcctResp) error {
+			return errors.New("disk full")
+		}),
+		SaveState: func(s *RotationState) error {
+			saved = *s
+			return nil
+		},
+	}
+	if _, err := client.RotateServiceAccount(
+		context.Background(), old, opts); err == nil {
+		t.Fatal("Expected the sink to fail")
+	}
+	lost := saved.NewId
+	if saved.Phase != RotationCreated || f.serviceAccount(lost) == nil {
+		t.Fatalf("Unexpected state: %+v", saved)
+	}
+
+	// Resume, then interrupt again during the grace period.
+	var secret string
+	opts.Sink = CredentialSinkFunc(func(_ context.Context, _ string,
+		creds *CreateServiceAcctResp) error {
+		secret = creds.Secret
+		return nil
+	})
+	opts.GracePeriod = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	state := saved
+	if _, err := client.ResumeRotation(ctx, &state, opts); !errors.Is(err,
+		context.DeadlineExceeded) || saved.Phase != RotationOldDisabled {
+		t.Fatalf("Expected the rotation to stop in the grace period; got %v", err)
+	}
+	if f.serviceAccount(lost) != nil || secret != "secret-"+saved.NewId {
+		t.Errorf("Expected the new account to be recreated: %+v", saved)
+	}
+	if f.serviceAccount(old).Enabled {
+		t.Error("Expected the old service account to be disabled")
+	}
+
+	state = saved
+	earlier := state.DisabledAt.Add(-time.Hour)
+	state.DisabledAt = &earlier
+	if _, err := client.ResumeRotation(
+		context.Background(), &state, opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if f.serviceAccount(old) != nil || state.Phase != RotationCompleted {
+		t.Errorf("Expected the rotation to complete: %+v", state)
+	}
+}
+
+func TestRotationNameTaken(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+	old := f.addServiceAccount(ServiceAcct{
+		Name: "app", Enabled: true, Permissions: []string{p}})
+	other := f.addServiceAccount(ServiceAcct{
+		Name: "other", Enabled: true, Permissions: []string{p}})
+
+	for _, name := range []string{"other", "app"} {
+		opts := &RotationOptions{
+			NewName: name,
+			Sink: CredentialSinkFunc(func(context.Context, string,
+				*CreateServiceAcctResp) error {
+				return nil
+			}),
+		}
+
+		state, err := client.RotateServiceAccount(context.Background(), old, opts)
+		if !IsConflict(err) || state.Phase != RotationPending {
+			t.Fatalf("%s: expected a name conflict; got %v", name, err)
+		}
+		if _, err = client.ResumeRotation(
+			context.Background(), state, opts); !IsConflict(err) {
+			t.Fatalf("%s: expected a name conflict on resume; got %v", name, err)
+		}
+		if f.serviceAccount(old) == nil || f.serviceAccount(other) == nil {
+			t.Fatalf("%s: expected existing accounts to be kept", name)
+		}
+	}
+}
+
+func TestRotationKeepsExpiration(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+	expires := NewTimestamp(time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second))
+	old := f.addServiceAccount(ServiceAcct{Name: "app", Enabled: true,
+		Permissions: []string{p}, ExpirationDate: expires})
+
+	state, err := client.RotateServiceAccount(context.Background(), old,
+		&RotationOptions{Sink: CredentialSinkFunc(func(context.Context, string,
+			*CreateServiceAcctResp) error {
+			return nil
+		})})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := f.serviceAccount(state.NewId).ExpirationDate; got != expires {
+		t.Errorf("Expected expiration %s; got %s", expires, got)
+	}
+}
+
+func TestRotationInterruptedWhileCreating(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+	old := f.addServiceAccount(ServiceAcct{
+		Name: "app", Enabled: true, Permissions: []string{p}})
+
+	// The process dies after the new account was created, before its ID was
+	// saved.
+	var saved RotationState
+	opts := &RotationOptions{
+		NewName: "app-2",
+		Sink: CredentialSinkFunc(func(context.Context, string,
+			*CreateServiceAcctResp) error {
+			return nil
+		}),
+		SaveState: func(s *RotationState) error {
+			if s.Phase == RotationCreated {
+				return errors.New("killed")
+			}
+			saved = *s
+			return nil
+		},
+	}
+	state, err := client.RotateServiceAccount(context.Background(), old, opts)
+	if err == nil || !saved.Creating || saved.NewId != "" {
+		t.Fatalf("Unexpected saved state: %+v, %v", saved, err)
+	}
+	orphan := state.NewId
+
+	opts.SaveState = nil
+	if state, err = client.ResumeRotation(
+		context.Background(), &saved, opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if f.serviceAccount(orphan) != nil || f.serviceAccount(old) != nil ||
+		f.serviceAccount(state.NewId).Name != "app-2" {
+		t.Errorf("Expected the orphaned account to be replaced: %+v", state)
+	}
+}
+
+func TestRotationMissingDisabledAt(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+	old := f.addServiceAccount(ServiceAcct{Name: "app", Permissions: []string{p}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	state := &RotationState{OldId: old, Phase: RotationOldDisabled}
+	_, err := client.ResumeRotation(ctx, state, &RotationOptions{
+		GracePeriod: time.Hour,
+		Sink: CredentialSinkFunc(func(context.Context, string,
+			*CreateServiceAcctResp) error {
+			return nil
+		}),
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || state.DisabledAt == nil {
+		t.Fatalf("Expected the grace period to start now; got %v", err)
+	}
+	if f.serviceAccount(old) == nil {
+		t.Error("Expected the old service account to be kept")
+	}
+}
+
+func TestRotationExpiredAccount(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+	old := f.addServiceAccount(ServiceAcct{Name: "app", Enabled: true,
+		Permissions:    []string{p},
+		ExpirationDate: NewTimestamp(time.Now().Add(-time.Hour))})
+
+	opts := &RotationOptions{Sink: CredentialSinkFunc(func(context.Context,
+		string, *CreateServiceAcctResp) error {
+		return nil
+	})}
+	state, err := client.RotateServiceAccount(context.Background(), old, opts)
+	if err == nil || state.Phase != RotationPending || state.Creating {
+		t.Fatalf("Expected the expired account to be reported; got %v", err)
+	}
+
+	expires := NewTimestamp(time.Now().Add(24 * time.Hour).Truncate(time.Second))
+	opts.ExpirationDate = expires
+	if state, err = client.ResumeRotation(
+		context.Background(), state, opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := f.serviceAccount(state.NewId).ExpirationDate; got != expires {
+		t.Errorf("Expected expiration %s; got %s", expires, got)
+	}
+}
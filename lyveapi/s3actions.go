@@ -0,0 +1,173 @@
+package lyveapi
+
+import (
+	"sort"
+	"strings"
+)
+
+// s3ActionScope describes the kind of resource an S3 action operates on.
+type s3ActionScope uint8
+
+const (
+	scopeService s3ActionScope = iota // the action applies to the account
+	scopeBucket                       // the action applies to a bucket
+	scopeObject                       // the action applies to an object
+)
+
+// s3Actions lists the S3 actions which may be used in Lyve Cloud permission
+// policies and the kind of resource each one applies to.
+var s3Actions = map[string]s3ActionScope{
+	"s3:ListAllMyBuckets":                 scopeService,
+	"s3:CreateBucket":                     scopeBucket,
+	"s3:DeleteBucket":                     scopeBucket,
+	"s3:DeleteBucketPolicy":               scopeBucket,
+	"s3:GetBucketAcl":                     scopeBucket,
+	"s3:GetBucketCORS":                    scopeBucket,
+	"s3:GetBucketLocation":                scopeBucket,
+	"s3:GetBucketLogging":                 scopeBucket,
+	"s3:GetBucketNotification":            scopeBucket,
+	"s3:GetBucketObjectLockConfiguration": scopeBucket,
+	"s3:GetBucketPolicy":                  scopeBucket,
+	"s3:GetBucketTagging":                 scopeBucket,
+	"s3:GetBucketVersioning":              scopeBucket,
+	"s3:GetEncryptionConfiguration":       scopeBucket,
+	"s3:GetLifecycleConfiguration":        scopeBucket,
+	"s3:ListBucket":                       scopeBucket,
+	"s3:ListBucketMultipartUploads":       scopeBucket,
+	"s3:ListBucketVersions":               scopeBucket,
+	"s3:PutBucketAcl":                     scopeBucket,
+	"s3:PutBucketCORS":                    scopeBucket,
+	"s3:PutBucketLogging":                 scopeBucket,
+	"s3:PutBucketNotification":            scopeBucket,
+	"s3:PutBucketObjectLockConfiguration": scopeBucket,
+	"s3:PutBucketPolicy":                  scopeBucket,
+	"s3:PutBucketTagging":                 scopeBucket,
+	"s3:PutBucketVersioning":              scopeBucket,
+	"s3:PutEncryptionConfiguration":       scopeBucket,
+	"s3:PutLifecycleConfiguration":        scopeBucket,
+	"s3:DeleteBucketOwnershipControls":    scopeBucket,
+	"s3:GetBucketOwnershipControls":       scopeBucket,
+	"s3:GetBucketPolicyStatus":            scopeBucket,
+	"s3:GetReplicationConfiguration":      scopeBucket,
+	"s3:PutBucketOwnershipControls":       scopeBucket,
+	"s3:PutReplicationConfiguration":      scopeBucket,
+	"s3:AbortMultipartUpload":             scopeObject,
+	"s3:BypassGovernanceRetention":        scopeObject,
+	"s3:DeleteObject":                     scopeObject,
+	"s3:DeleteObjectTagging":              scopeObject,
+	"s3:DeleteObjectVersion":              scopeObject,
+	"s3:DeleteObjectVersionTagging":       scopeObject,
+	"s3:GetObject":                        scopeObject,
+	"s3:GetObjectAcl":                     scopeObject,
+	"s3:GetObjectLegalHold":               scopeObject,
+	"s3:GetObjectRetention":               scopeObject,
+	"s3:GetObjectTagging":                 scopeObject,
+	"s3:GetObjectVersion":                 scopeObject,
+	"s3:GetObjectVersionAcl":              scopeObject,
+	"s3:GetObjectVersionTagging":          scopeObject,
+	"s3:ListMultipartUploadParts":         scopeObject,
+	"s3:PutObject":                        scopeObject,
+	"s3:PutObjectAcl":                     scopeObject,
+	"s3:PutObjectLegalHold":               scopeObject,
+	"s3:PutObjectRetention":               scopeObject,
+	"s3:PutObjectTagging":                 scopeObject,
+	"s3:PutObjectVersionAcl":              scopeObject,
+	"s3:PutObjectVersionTagging":          scopeObject,
+	"s3:ReplicateObject":                  scopeObject,
+	"s3:RestoreObject":                    scopeObject,
+}
+
+// actionsByLowerName maps lowercase action names to their canonical spelling,
+// since action names in policy documents are case-insensitive.
+var actionsByLowerName = func() map[string]string {
+	m := make(map[string]string, len(s3Actions))
+	for a := range s3Actions {
+		m[strings.ToLower(a)] = a
+	}
+	return m
+}()
+
+// KnownS3Actions returns the names of all S3 actions which may be used in
+// permission policies, in lexical order.
+func KnownS3Actions() []string {
+	actions := make([]string, 0, len(s3Actions))
+	for a := range s3Actions {
+		actions = append(actions, a)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// canonicalActionName returns the canonical spelling of a known action, or
+// the action unchanged if it is not known.
+func canonicalActionName(action string) string {
+	if a, ok := actionsByLowerName[strings.ToLower(action)]; ok {
+		return a
+	}
+	return action
+}
+
+// expandActionPattern returns the known actions matched by pattern, which may
+// contain wildcards, in lexical order.
+func expandActionPattern(pattern string) []string {
+	var matched []string
+	for _, a := range KnownS3Actions() {
+		if actionMatch(pattern, a) {
+			matched = append(matched, a)
+		}
+	}
+	return matched
+}
+
+// actionMatch reports whether an action pattern matches an action. Action
+// names are compared case-insensitively.
+func actionMatch(pattern, action string) bool {
+	return wildcardMatch(strings.ToLower(pattern), strings.ToLower(action))
+}
+
+// wildcardMatch reports whether s matches pattern, where '*' matches any
+// sequence of characters, including an empty one, and '?' matches any single
+// character.
+func wildcardMatch(pattern, s string) bool {
+	return wildcardMatchLiteral([]rune(pattern), nil, s)
+}
+
+// wildcardMatchLiteral is wildcardMatch for a pattern of runes, where the
+// runes marked in literal match only themselves. literal may be nil.
+func wildcardMatchLiteral(p []rune, literal []bool, s string) bool {
+	return wildcardMatchRunes(p, literal, []rune(s), 0)
+}
+
+// wildcardMatchRunes implements wildcardMatch. The runes of p marked in
+// literal match only themselves, and a '?' in p never matches the rune
+// opaque in r, unless opaque is zero.
+func wildcardMatchRunes(p []rune, literal []bool, r []rune, opaque rune) bool {
+	wild := func(i int, c rune) bool {
+		return p[i] == c && (literal == nil || !literal[i])
+	}
+
+	var pi, si int
+	star, mark := -1, 0
+	for si < len(r) {
+		switch {
+		case pi < len(p) && wild(pi, '*'):
+			star, mark = pi, si
+			pi++
+		case pi < len(p) && ((wild(pi, '?') && r[si] != opaque) ||
+			p[pi] == r[si]):
+			pi++
+			si++
+		case star >= 0:
+			pi = star + 1
+			mark++
+			si = mark
+		default:
+			return false
+		}
+	}
+
+	for pi < len(p) && wild(pi, '*') {
+		pi++
+	}
+	return pi == len(p)
+}
@@ -0,0 +1,171 @@
+package lyveapi
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrPermissionInUse is matched by errors.Is when a permission cannot be
+// deleted safely because service accounts still reference it.
+var ErrPermissionInUse = errors.New("permission is in use by service accounts")
+
+// ServiceAcctRef identifies a service account.
+type ServiceAcctRef struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (r ServiceAcctRef) String() string {
+	if r.Name == "" {
+		return r.Id
+	}
+	return r.Name + " (" + r.Id + ")"
+}
+
+// PermissionInUseError is returned by DeletePermissionSafely when service
+// accounts reference the permission and it may not be detached from them.
+type PermissionInUseError struct {
+	// PermissionId is the ID of the permission which was not deleted.
+	PermissionId string
+	// ServiceAccounts lists the accounts referencing the permission.
+	ServiceAccounts []ServiceAcctRef
+	// SolePermission is true when the deletion was refused in cascade mode,
+	// because the permission is the only one of each listed account. An
+	// account cannot be left without permissions.
+	SolePermission bool
+}
+
+func (e *PermissionInUseError) Error() string {
+	refs := make([]string, len(e.ServiceAccounts))
+	for i, r := range e.ServiceAccounts {
+		refs[i] = r.String()
+	}
+
+	msg := "permission " + e.PermissionId + " is in use by service accounts: "
+	if e.SolePermission {
+		msg = "permission " + e.PermissionId + " is the only permission of " +
+			"service accounts: "
+	}
+	return msg + strings.Join(refs, ", ")
+}
+
+// Is reports whether target is ErrPermissionInUse.
+func (e *PermissionInUseError) Is(target error) bool {
+	return target == ErrPermissionInUse
+}
+
+// DeleteMode selects how DeletePermissionSafely treats service accounts which
+// reference the permission being deleted.
+type DeleteMode int
+
+const (
+	// DeleteRefuseIfInUse refuses to delete a permission which is referenced
+	// by any service account.
+	DeleteRefuseIfInUse DeleteMode = iota
+	// DeleteCascade first detaches the permission from the service accounts
+	// referencing it.
+	DeleteCascade
+)
+
+// PermissionDeleteReport describes what DeletePermissionSafely did.
+type PermissionDeleteReport struct {
+	// PermissionId is the ID of the permission.
+	PermissionId string `json:"permissionId"`
+	// Dependents lists the service accounts which referenced the permission.
+	Dependents []ServiceAcctRef `json:"dependents"`
+	// Detached lists the service accounts the permission was detached from.
+	Detached []ServiceAcctRef `json:"detached"`
+	// Deleted is true when the permission was deleted.
+	Deleted bool `json:"deleted"`
+}
+
+// PermissionDependents returns the service accounts which reference the
+// permission with the given ID. Since listings may omit the permissions of
+// each account, every service account is fetched individually.
+func (client *Client) PermissionDependents(
+	permissionId string) ([]ServiceAcct, error) {
+	accts, err := client.ListServiceAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var dependents []ServiceAcct
+	for _, a := range *accts {
+		acct, err := client.GetServiceAccount(a.Id)
+		if err != nil {
+			return nil, err
+		}
+		if stringSet(acct.Permissions)[permissionId] {
+			dependents = append(dependents, *acct)
+		}
+	}
+	return dependents, nil
+}
+
+// DeletePermissionSafely deletes the permission with the given ID after
+// checking which service accounts reference it. Unlike DeletePermission, it
+// does not leave service accounts referring to a missing permission.
+//
+// With DeleteRefuseIfInUse, a *PermissionInUseError listing the dependent
+// service accounts is returned if there are any, and nothing is changed.
+//
+// With DeleteCascade, the permission is detached from each dependent service
+// account with DetachPermissions before it is deleted. If the permission is
+// the only one of any dependent account, a *PermissionInUseError listing those
+// accounts is returned and nothing is changed, since an account cannot be
+// left without permissions.
+//
+// The returned report describes everything that was changed, including when
+// an error interrupts a cascade part way through.
+func (client *Client) DeletePermissionSafely(permissionId string,
+	mode DeleteMode) (*PermissionDeleteReport, error) {
+	report := &PermissionDeleteReport{PermissionId: permissionId}
+
+	// Check that the permission exists before searching for dependents.
+	if _, err := client.GetPermission(permissionId); err != nil {
+		return report, err
+	}
+
+	dependents, err := client.PermissionDependents(permissionId)
+	if err != nil {
+		return report, err
+	}
+
+	var sole []ServiceAcctRef
+	for _, a := range dependents {
+		ref := ServiceAcctRef{Id: a.Id, Name: a.Name}
+		report.Dependents = append(report.Dependents, ref)
+		if len(a.Permissions) == 1 {
+			sole = append(sole, ref)
+		}
+	}
+
+	switch {
+	case len(dependents) > 0 && mode != DeleteCascade:
+		return report, &PermissionInUseError{
+			PermissionId:    permissionId,
+			ServiceAccounts: report.Dependents,
+		}
+	case len(sole) > 0:
+		return report, &PermissionInUseError{
+			PermissionId:    permissionId,
+			ServiceAccounts: sole,
+			SolePermission:  true,
+		}
+	}
+
+	for _, a := range dependents {
+		if _, err = client.DetachPermissions(a.Id, permissionId); err != nil {
+			return report, err
+		}
+		report.Detached = append(report.Detached,
+			ServiceAcctRef{Id: a.Id, Name: a.Name})
+	}
+
+	if err = client.DeletePermission(permissionId); err != nil {
+		return report, err
+	}
+
+	report.Deleted = true
+	return report, nil
+}
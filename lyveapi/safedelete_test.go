@@ -0,0 +1,63 @@
+package lyveapi
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestDeletePermissionSafely(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p1 := f.addPermission(Permission{Name: "p1", Type: AllBuckets, Actions: ReadOnly})
+	p2 := f.addPermission(Permission{Name: "p2", Type: AllBuckets, Actions: WriteOnly})
+	p3 := f.addPermission(Permission{Name: "p3", Type: AllBuckets, Actions: WriteOnly})
+	a1 := f.addServiceAccount(ServiceAcct{Name: "a1", Permissions: []string{p1, p2}})
+	a2 := f.addServiceAccount(ServiceAcct{Name: "a2", Permissions: []string{p2}})
+
+	report, err := client.DeletePermissionSafely(p1, DeleteRefuseIfInUse)
+	var inUse *PermissionInUseError
+	if !errors.As(err, &inUse) || !errors.Is(err, ErrPermissionInUse) ||
+		len(inUse.ServiceAccounts) != 1 || inUse.ServiceAccounts[0].Id != a1 {
+		t.Fatalf("Expected a *PermissionInUseError; got %v", err)
+	}
+	if report.Deleted || f.permission(p1) == nil {
+		t.Error("Expected the permission not to be deleted")
+	}
+
+	report, err = client.DeletePermissionSafely(p1, DeleteCascade)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !report.Deleted || f.permission(p1) != nil ||
+		!reflect.DeepEqual(report.Detached, []ServiceAcctRef{{Id: a1, Name: "a1"}}) {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if perms := f.serviceAccount(a1).Permissions; !reflect.DeepEqual(perms, []string{p2}) {
+		t.Errorf("Expected the permission to be detached: %v", perms)
+	}
+
+	// p2 is now the only permission of both accounts, so it cannot be
+	// detached.
+	updates := f.count(http.MethodPut, "/service-accounts")
+	_, err = client.DeletePermissionSafely(p2, DeleteCascade)
+	if !errors.As(err, &inUse) || !inUse.SolePermission ||
+		!reflect.DeepEqual(inUse.ServiceAccounts, []ServiceAcctRef{
+			{Id: a1, Name: "a1"}, {Id: a2, Name: "a2"}}) {
+		t.Errorf("Expected a sole permission error; got %v", err)
+	}
+	if n := f.count(http.MethodPut, "/service-accounts"); n != updates {
+		t.Errorf("Expected no service accounts to be changed")
+	}
+
+	if report, err = client.DeletePermissionSafely(p3, DeleteRefuseIfInUse); err != nil ||
+		!report.Deleted || len(report.Dependents) != 0 {
+		t.Errorf("Unexpected result: %+v, %v", report, err)
+	}
+
+	if _, err = client.DeletePermissionSafely("missing", DeleteCascade); !IsNotFound(err) {
+		t.Errorf("Expected a not found error; got %v", err)
+	}
+}
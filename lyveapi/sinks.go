@@ -0,0 +1,368 @@
+package lyveapi
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrCredentialEntryExists is matched by errors.Is when a credential sink
+// refuses to replace an existing entry because overwriting was not requested.
+var ErrCredentialEntryExists = errors.New("credential entry already exists")
+
+// CredentialEntryExistsError is returned by the credential sinks in this
+// package when the entry they would write already exists.
+type CredentialEntryExistsError struct {
+	// Path is the file which holds the entry.
+	Path string
+	// Entry names the entry, such as a profile or a variable name.
+	Entry string
+}
+
+func (e *CredentialEntryExistsError) Error() string {
+	return "credential entry " + strconv.Quote(e.Entry) + " already exists in " +
+		e.Path
+}
+
+// Is reports whether target is ErrCredentialEntryExists.
+func (e *CredentialEntryExistsError) Is(target error) bool {
+	return target == ErrCredentialEntryExists
+}
+
+// credentialFileMode is the mode of files written by credential sinks, which
+// hold secrets.
+const credentialFileMode fs.FileMode = 0o600
+
+// AWSCredentialsSink writes credentials as a profile of an AWS shared
+// credentials file, such as ~/.aws/credentials, keeping the other profiles.
+type AWSCredentialsSink struct {
+	// Path is the credentials file.
+	Path string
+	// Profile is the name of the profile. If empty, the name of the service
+	// account is used.
+	Profile string
+	// Overwrite replaces an existing profile of the same name.
+	Overwrite bool
+}
+
+// StoreCredentials implements CredentialSink.
+func (s *AWSCredentialsSink) StoreCredentials(ctx context.Context, name string,
+	creds *CreateServiceAcctResp) error {
+	return updateINIFile(ctx, s.Path, orDefault(s.Profile, name), []iniKey{
+		{"aws_access_key_id", creds.AccessKey},
+		{"aws_secret_access_key", creds.Secret},
+	}, s.Overwrite)
+}
+
+// RcloneSink writes credentials as an S3 remote of an rclone configuration
+// file, keeping the other remotes.
+type RcloneSink struct {
+	// Path is the configuration file, usually ~/.config/rclone/rclone.conf.
+	Path string
+	// Remote is the name of the remote. If empty, the name of the service
+	// account is used.
+	Remote string
+	// Endpoint is the S3 endpoint URL of the Lyve Cloud region.
+	Endpoint string
+	// Region is the name of the region, if required by the endpoint.
+	Region string
+	// Overwrite replaces an existing remote of the same name.
+	Overwrite bool
+}
+
+// StoreCredentials implements CredentialSink.
+func (s *RcloneSink) StoreCredentials(ctx context.Context, name string,
+	creds *CreateServiceAcctResp) error {
+	keys := []iniKey{
+		{"type", "s3"},
+		{"provider", "LyveCloud"},
+		{"access_key_id", creds.AccessKey},
+		{"secret_access_key", creds.Secret},
+		{"endpoint", s.Endpoint},
+	}
+	if s.Region != "" {
+		keys = append(keys, iniKey{"region", s.Region})
+	}
+	return updateINIFile(ctx, s.Path, orDefault(s.Remote, name), keys,
+		s.Overwrite)
+}
+
+// S3cmdSink writes credentials as a section of an s3cmd configuration file,
+// keeping the other sections.
+type S3cmdSink struct {
+	// Path is the configuration file, usually ~/.s3cfg.
+	Path string
+	// Section is the name of the section. If empty, "default" is used, which
+	// is the section s3cmd reads.
+	Section string
+	// Endpoint is the host name of the S3 endpoint of the Lyve Cloud region,
+	// without a scheme.
+	Endpoint string
+	// Overwrite replaces an existing section of the same name.
+	Overwrite bool
+}
+
+// StoreCredentials implements CredentialSink.
+func (s *S3cmdSink) StoreCredentials(ctx context.Context, name string,
+	creds *CreateServiceAcctResp) error {
+	return updateINIFile(ctx, s.Path, orDefault(s.Section, "default"),
+		[]iniKey{
+			{"access_key", creds.AccessKey},
+			{"secret_key", creds.Secret},
+			{"host_base", s.Endpoint},
+			{"host_bucket", s.Endpoint},
+			{"use_https", "True"},
+		}, s.Overwrite)
+}
+
+// DotenvSink writes credentials as AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+// variables, and AWS_ENDPOINT_URL if an endpoint is given, to a dotenv file,
+// keeping its other variables.
+type DotenvSink struct {
+	// Path is the dotenv file.
+	Path string
+	// Prefix is prepended to the variable names.
+	Prefix string
+	// Endpoint is the S3 endpoint URL of the Lyve Cloud region. If empty,
+	// AWS_ENDPOINT_URL is not written.
+	Endpoint string
+	// Overwrite replaces existing variables of the same names.
+	Overwrite bool
+}
+
+// StoreCredentials implements CredentialSink.
+func (s *DotenvSink) StoreCredentials(ctx context.Context, name string,
+	creds *CreateServiceAcctResp) error {
+	vars := []iniKey{
+		{s.Prefix + "AWS_ACCESS_KEY_ID", creds.AccessKey},
+		{s.Prefix + "AWS_SECRET_ACCESS_KEY", creds.Secret},
+	}
+	if s.Endpoint != "" {
+		vars = append(vars, iniKey{s.Prefix + "AWS_ENDPOINT_URL", s.Endpoint})
+	}
+
+	return updateFile(ctx, s.Path, func(data []byte) ([]byte, error) {
+		var lines []string
+		if len(data) > 0 {
+			lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		}
+
+		for _, v := range vars {
+			found := false
+			for i, line := range lines {
+				if dotenvName(line) != v.name {
+					continue
+				}
+				if !s.Overwrite {
+					return nil, &CredentialEntryExistsError{
+						Path: s.Path, Entry: v.name}
+				}
+				lines[i], found = v.name+"="+dotenvValue(v.value), true
+			}
+			if !found {
+				lines = append(lines, v.name+"="+dotenvValue(v.value))
+			}
+		}
+		return []byte(strings.Join(lines, "\n") + "\n"), nil
+	})
+}
+
+// dotenvName returns the name of the variable assigned by a line of a dotenv
+// file, or an empty string.
+func dotenvName(line string) string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "export ")
+	name, _, ok := strings.Cut(line, "=")
+	if !ok || strings.HasPrefix(line, "#") {
+		return ""
+	}
+	return strings.TrimSpace(name)
+}
+
+var dotenvPlainRe = regexp.MustCompile(`^[A-Za-z0-9_./:+=-]*$`)
+
+// dotenvValue quotes a value unless it consists only of safe characters.
+func dotenvValue(v string) string {
+	if dotenvPlainRe.MatchString(v) {
+		return v
+	}
+	return strconv.Quote(v)
+}
+
+// KubernetesSecretSink writes credentials as the manifest of a Kubernetes
+// Secret, holding AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, and
+// AWS_ENDPOINT_URL if an endpoint is given. The file holds only the manifest.
+type KubernetesSecretSink struct {
+	// Path is the manifest file.
+	Path string
+	// SecretName is the name of the Secret. If empty, it is derived from the
+	// name of the service account.
+	SecretName string
+	// Namespace is the namespace of the Secret, if any.
+	Namespace string
+	// Endpoint is the S3 endpoint URL of the Lyve Cloud region. If empty,
+	// AWS_ENDPOINT_URL is not written.
+	Endpoint string
+	// Overwrite replaces an existing manifest.
+	Overwrite bool
+}
+
+// StoreCredentials implements CredentialSink.
+func (s *KubernetesSecretSink) StoreCredentials(ctx context.Context,
+	name string, creds *CreateServiceAcctResp) error {
+	secretName := s.SecretName
+	if secretName == "" {
+		secretName = kubernetesName(name)
+	}
+
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\nkind: Secret\nmetadata:\n")
+	b.WriteString("  name: " + strconv.Quote(secretName) + "\n")
+	if s.Namespace != "" {
+		b.WriteString("  namespace: " + strconv.Quote(s.Namespace) + "\n")
+	}
+	b.WriteString("type: Opaque\nstringData:\n")
+	b.WriteString("  AWS_ACCESS_KEY_ID: " + strconv.Quote(creds.AccessKey) + "\n")
+	b.WriteString("  AWS_SECRET_ACCESS_KEY: " + strconv.Quote(creds.Secret) + "\n")
+	if s.Endpoint != "" {
+		b.WriteString("  AWS_ENDPOINT_URL: " + strconv.Quote(s.Endpoint) + "\n")
+	}
+
+	return updateFile(ctx, s.Path, func(data []byte) ([]byte, error) {
+		if data != nil && !s.Overwrite {
+			return nil, &CredentialEntryExistsError{
+				Path: s.Path, Entry: secretName}
+		}
+		return []byte(b.String()), nil
+	})
+}
+
+var kubernetesNameRe = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// kubernetesName turns a service account name into a valid object name,
+// which consists of lower case alphanumeric characters and '-'.
+func kubernetesName(name string) string {
+	n := kubernetesNameRe.ReplaceAllString(strings.ToLower(name), "-")
+	if n = strings.Trim(n, "-"); len(n) > 253 {
+		n = strings.TrimRight(n[:253], "-")
+	}
+	if n == "" {
+		return "lyvecloud-credentials"
+	}
+	return n
+}
+
+// iniKey is a key and value written to a credentials file.
+type iniKey struct {
+	name, value string
+}
+
+// updateINIFile adds a section with the given keys to an INI file, or
+// replaces the section if it exists and overwrite is true. Other sections are
+// kept as they are.
+func updateINIFile(ctx context.Context, path, section string,
+	keys []iniKey, overwrite bool) error {
+	return updateFile(ctx, path, func(data []byte) ([]byte, error) {
+		var lines []string
+		if len(data) > 0 {
+			lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		}
+
+		block := []string{"[" + section + "]"}
+		for _, k := range keys {
+			block = append(block, k.name+" = "+k.value)
+		}
+
+		start := -1
+		for i, line := range lines {
+			if strings.TrimSpace(line) == "["+section+"]" {
+				start = i
+				break
+			}
+		}
+
+		if start < 0 {
+			if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+				lines = append(lines, "")
+			}
+			lines = append(lines, block...)
+			return []byte(strings.Join(lines, "\n") + "\n"), nil
+		}
+
+		if !overwrite {
+			return nil, &CredentialEntryExistsError{Path: path, Entry: section}
+		}
+
+		end := len(lines)
+		for i := start + 1; i < len(lines); i++ {
+			if strings.HasPrefix(strings.TrimSpace(lines[i]), "[") {
+				end = i
+				block = append(block, "")
+				break
+			}
+		}
+
+		out := append(append(lines[:start:start], block...), lines[end:]...)
+		return []byte(strings.Join(out, "\n") + "\n"), nil
+	})
+}
+
+// updateFile passes the contents of the file at path, or nil if it does not
+// exist, to update, and atomically replaces the file with the result. The
+// file is written to a temporary file in the same directory, which is renamed
+// over the original, so readers never see a partial file. The file is always
+// left readable only by its owner.
+func updateFile(ctx context.Context, path string,
+	update func(data []byte) ([]byte, error)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if path == "" {
+		return errors.New("no credentials file path provided")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if data == nil && err == nil {
+		data = []byte{}
+	}
+
+	if data, err = update(data); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = tmp.Chmod(credentialFileMode); err == nil {
+		if _, err = tmp.Write(data); err == nil {
+			err = tmp.Sync()
+		}
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// orDefault returns s, or def if s is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
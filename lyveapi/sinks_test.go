@@ -0,0 +1,171 @@
+package lyveapi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var sinkCreds = &CreateServiceAcctResp{
+	Id: "sa-1", AccessKey: "AKEXAMPLE", Secret: "s3cr3t/key+1"}
+
+// readSinkFile returns the contents of a file written by a sink, after
+// checking that only its owner can access it.
+func readSinkFile(t *testing.T, path string) string {
+	t.Helper()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600; got %v", fi.Mode().Perm())
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return string(b)
+}
+
+func TestAWSCredentialsSink(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "credentials")
+	existing := "[default]\naws_access_key_id = OLD\n\n[other]\nregion = x\n"
+	if err := os.WriteFile(path, []byte(existing), 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	sink := &AWSCredentialsSink{Path: path}
+	if err := sink.StoreCredentials(ctx, "app", sinkCreds); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := existing + "\n[app]\naws_access_key_id = AKEXAMPLE\n" +
+		"aws_secret_access_key = s3cr3t/key+1\n"
+	if got := readSinkFile(t, path); got != expected {
+		t.Errorf("Unexpected contents:\n%s", got)
+	}
+
+	sink.Profile = "default"
+	err := sink.StoreCredentials(ctx, "app", sinkCreds)
+	if !errors.Is(err, ErrCredentialEntryExists) {
+		t.Fatalf("Expected ErrCredentialEntryExists; got %v", err)
+	}
+
+	sink.Overwrite = true
+	if err = sink.StoreCredentials(ctx, "app", sinkCreds); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected = "[default]\naws_access_key_id = AKEXAMPLE\n" +
+		"aws_secret_access_key = s3cr3t/key+1\n\n[other]\nregion = x\n\n" +
+		"[app]\naws_access_key_id = AKEXAMPLE\n" +
+		"aws_secret_access_key = s3cr3t/key+1\n"
+	if got := readSinkFile(t, path); got != expected {
+		t.Errorf("Unexpected contents:\n%s", got)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected temporary files to be removed: %v", entries)
+	}
+}
+
+func TestConfigSinks(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tests := []struct {
+		sink     CredentialSink
+		path     string
+		expected string
+	}{
+		{&RcloneSink{Path: filepath.Join(dir, "rclone.conf"),
+			Endpoint: "https://s3.example.com"},
+			filepath.Join(dir, "rclone.conf"),
+			"[app]\ntype = s3\nprovider = LyveCloud\n" +
+				"access_key_id = AKEXAMPLE\nsecret_access_key = s3cr3t/key+1\n" +
+				"endpoint = https://s3.example.com\n"},
+		{&S3cmdSink{Path: filepath.Join(dir, "s3cfg"),
+			Endpoint: "s3.example.com"},
+			filepath.Join(dir, "s3cfg"),
+			"[default]\naccess_key = AKEXAMPLE\nsecret_key = s3cr3t/key+1\n" +
+				"host_base = s3.example.com\nhost_bucket = s3.example.com\n" +
+				"use_https = True\n"},
+		{&KubernetesSecretSink{Path: filepath.Join(dir, "secret.yaml"),
+			Namespace: "prod"},
+			filepath.Join(dir, "secret.yaml"),
+			"apiVersion: v1\nkind: Secret\nmetadata:\n  name: \"app\"\n" +
+				"  namespace: \"prod\"\ntype: Opaque\nstringData:\n" +
+				"  AWS_ACCESS_KEY_ID: \"AKEXAMPLE\"\n" +
+				"  AWS_SECRET_ACCESS_KEY: \"s3cr3t/key+1\"\n"},
+	}
+
+	for _, test := range tests {
+		ctx := context.Background()
+		if err := test.sink.StoreCredentials(ctx, "app", sinkCreds); err != nil {
+			t.Fatalf("%T: unexpected error: %v", test.sink, err)
+		}
+		if got := readSinkFile(t, test.path); got != test.expected {
+			t.Errorf("%T: unexpected contents:\n%s", test.sink, got)
+		}
+
+		err := test.sink.StoreCredentials(ctx, "app", sinkCreds)
+		if !errors.Is(err, ErrCredentialEntryExists) {
+			t.Errorf("%T: expected ErrCredentialEntryExists; got %v",
+				test.sink, err)
+		}
+	}
+}
+
+func TestDotenvSink(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path,
+		[]byte("# settings\nLOG_LEVEL=debug\n"), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	sink := &DotenvSink{Path: path, Endpoint: "https://s3.example.com"}
+	creds := &CreateServiceAcctResp{AccessKey: "AKEXAMPLE", Secret: "a b"}
+	if err := sink.StoreCredentials(ctx, "app", creds); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "# settings\nLOG_LEVEL=debug\nAWS_ACCESS_KEY_ID=AKEXAMPLE\n" +
+		"AWS_SECRET_ACCESS_KEY=\"a b\"\nAWS_ENDPOINT_URL=https://s3.example.com\n"
+	if got := readSinkFile(t, path); got != expected {
+		t.Errorf("Unexpected contents:\n%s", got)
+	}
+
+	if err := sink.StoreCredentials(ctx, "app", creds); !errors.Is(err,
+		ErrCredentialEntryExists) {
+		t.Fatalf("Expected ErrCredentialEntryExists; got %v", err)
+	}
+
+	sink.Overwrite = true
+	if err := sink.StoreCredentials(ctx, "app", sinkCreds); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := readSinkFile(t, path); strings.Count(got, "AWS_") != 3 ||
+		!strings.Contains(got, "AWS_SECRET_ACCESS_KEY=s3cr3t/key+1\n") {
+		t.Errorf("Expected the variables to be replaced:\n%s", got)
+	}
+}
+
+func TestKubernetesName(t *testing.T) {
+	t.Parallel()
+
+	if n := kubernetesName("Backup_Svc.Acct"); n != "backup-svc-acct" {
+		t.Errorf("Unexpected name %q", n)
+	}
+}
@@ -0,0 +1,307 @@
+package lyveapi
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownTemplate is returned when a policy template is not known.
+var ErrUnknownTemplate = errors.New("unknown policy template")
+
+// policyVariableNameRe describes the names of policy variables, such as
+// "aws:username", which may be used by the tenant isolation template.
+var policyVariableNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+:[A-Za-z0-9_-]+$`)
+
+// TemplateParam describes a parameter of a policy template.
+type TemplateParam struct {
+	// Name is the name of the parameter, such as "bucket".
+	Name string
+	// Description describes the parameter.
+	Description string
+	// Required is true when the parameter must be given a value.
+	Required bool
+	// Default is the value used when an optional parameter is not given.
+	Default string
+	// List is true when the parameter accepts a comma-separated list of
+	// values.
+	List bool
+}
+
+// PolicyTemplate is a parameterized permission for a common access pattern,
+// such as read-only access to a prefix of a bucket. Use Render to produce a
+// permission from a template.
+type PolicyTemplate struct {
+	// Name is the name of the template, such as "read-only-prefix".
+	Name string
+	// Description describes the access granted by the template.
+	Description string
+	// Params lists the parameters accepted by the template.
+	Params []TemplateParam
+
+	build func(args templateArgs) (*PolicyDocument, error)
+}
+
+// templateArgs holds the parameter values of a template, with defaults
+// applied.
+type templateArgs map[string]string
+
+func (a templateArgs) list(name string) []string {
+	var values []string
+	for _, v := range strings.Split(a[name], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// objectPattern returns the ARN matching objects of bucket beginning with
+// prefix.
+func (a templateArgs) objectPattern() string {
+	return ObjectARN(a["bucket"], a["prefix"]+"*")
+}
+
+var policyTemplates = []*PolicyTemplate{
+	{
+		Name: "read-only-prefix",
+		Description: "Allows listing and reading the objects beneath a " +
+			"prefix of a bucket.",
+		Params: []TemplateParam{
+			{Name: "bucket", Description: "name of the bucket", Required: true},
+			{Name: "prefix", Description: "key prefix, such as \"reports/\"",
+				Required: true},
+		},
+		build: func(args templateArgs) (*PolicyDocument, error) {
+			return NewPolicyBuilder().
+				Allow("s3:GetObject", "s3:GetObjectVersion",
+					"s3:GetObjectTagging").
+				On(args.objectPattern()).
+				Allow("s3:ListBucket").
+				On(BucketARN(args["bucket"])).
+				When("StringLike", "s3:prefix", args["prefix"]+"*").
+				Allow("s3:GetBucketLocation").
+				On(BucketARN(args["bucket"])).
+				Build()
+		},
+	},
+	{
+		Name: "write-only-dropbox",
+		Description: "Allows uploading objects beneath a prefix of a " +
+			"bucket, without allowing them to be listed, read or deleted.",
+		Params: []TemplateParam{
+			{Name: "bucket", Description: "name of the bucket", Required: true},
+			{Name: "prefix", Description: "key prefix, or empty for the " +
+				"whole bucket"},
+		},
+		build: func(args templateArgs) (*PolicyDocument, error) {
+			return NewPolicyBuilder().
+				Allow("s3:PutObject", "s3:AbortMultipartUpload",
+					"s3:ListMultipartUploadParts").
+				On(args.objectPattern()).
+				Build()
+		},
+	},
+	{
+		Name: "deny-delete",
+		Description: "Denies deleting objects beneath a prefix of a bucket, " +
+			"or bypassing their retention. Combine it with permissions " +
+			"allowing access to retain objects written through them.",
+		Params: []TemplateParam{
+			{Name: "bucket", Description: "name of the bucket", Required: true},
+			{Name: "prefix", Description: "key prefix, or empty for the " +
+				"whole bucket"},
+		},
+		build: func(args templateArgs) (*PolicyDocument, error) {
+			return NewPolicyBuilder().
+				Deny("s3:DeleteObject", "s3:DeleteObjectVersion",
+					"s3:BypassGovernanceRetention").
+				On(args.objectPattern()).
+				Deny("s3:PutLifecycleConfiguration", "s3:DeleteBucket").
+				On(BucketARN(args["bucket"])).
+				Build()
+		},
+	},
+	{
+		Name: "tenant-isolation",
+		Description: "Allows full access to objects beneath a per-tenant " +
+			"prefix of a shared bucket, where the prefix is the value of a " +
+			"policy variable, such as the user name.",
+		Params: []TemplateParam{
+			{Name: "bucket", Description: "name of the shared bucket",
+				Required: true},
+			{Name: "variable", Description: "policy variable naming the " +
+				"tenant", Default: "aws:username"},
+		},
+		build: func(args templateArgs) (*PolicyDocument, error) {
+			if !policyVariableNameRe.MatchString(args["variable"]) {
+				return nil, fmt.Errorf("%q is not a policy variable name",
+					args["variable"])
+			}
+
+			tenant := "${" + args["variable"] + "}"
+			return NewPolicyBuilder().
+				Allow("s3:GetObject", "s3:PutObject", "s3:DeleteObject",
+					"s3:AbortMultipartUpload", "s3:ListMultipartUploadParts").
+				On(ObjectARN(args["bucket"], tenant+"/*")).
+				Allow("s3:ListBucket").
+				On(BucketARN(args["bucket"])).
+				When("StringLike", "s3:prefix", tenant+"/*").
+				Build()
+		},
+	},
+	{
+		Name: "source-ip-restricted",
+		Description: "Allows access to buckets only from requests " +
+			"originating in the given networks.",
+		Params: []TemplateParam{
+			{Name: "buckets", Description: "names of the buckets",
+				Required: true, List: true},
+			{Name: "cidrs", Description: "allowed networks, such as " +
+				"\"10.0.0.0/8\"", Required: true, List: true},
+			{Name: "actions", Description: "one of \"read-only\", " +
+				"\"write-only\" or \"all-operations\"",
+				Default: string(ReadOnly)},
+		},
+		build: func(args templateArgs) (*PolicyDocument, error) {
+			actions, ok := structuredActions[Action(args["actions"])]
+			if !ok {
+				return nil, fmt.Errorf("%q is not a permission action",
+					args["actions"])
+			}
+
+			cidrs := args.list("cidrs")
+			for _, c := range cidrs {
+				if _, err := netip.ParsePrefix(c); err != nil {
+					return nil, err
+				}
+			}
+
+			var resources []string
+			for _, b := range args.list("buckets") {
+				resources = append(resources, BucketARN(b), ObjectARN(b, "*"))
+			}
+
+			return NewPolicyBuilder().
+				Allow(actions...).
+				On(resources...).
+				When("IpAddress", "aws:SourceIp", cidrs...).
+				Build()
+		},
+	},
+}
+
+// PolicyTemplates returns the available policy templates, sorted by name.
+func PolicyTemplates() []*PolicyTemplate {
+	templates := append([]*PolicyTemplate(nil), policyTemplates...)
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates
+}
+
+// LookupPolicyTemplate returns the policy template with the given name.
+func LookupPolicyTemplate(name string) (*PolicyTemplate, bool) {
+	for _, t := range policyTemplates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// RenderPolicyTemplate looks up a policy template by name and renders it, as
+// described by PolicyTemplate.Render. ErrUnknownTemplate is returned if there
+// is no such template.
+func RenderPolicyTemplate(template, name string,
+	params map[string]string) (*Permission, error) {
+	t, ok := LookupPolicyTemplate(template)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTemplate, template)
+	}
+	return t.Render(name, params)
+}
+
+// Render produces a policy permission with the given name from the template
+// and its parameters, ready to be passed to CreatePermission. The description
+// of the permission records the template name and the parameter values, for
+// example "template=read-only-prefix; bucket=alpha; prefix=reports/".
+//
+// A *ValidationError is returned when a required parameter is missing, an
+// unknown parameter is given, or a bucket name is invalid. The rendered
+// permission is validated and linted, and any finding of error severity is
+// returned as a *LintError.
+func (t *PolicyTemplate) Render(name string,
+	params map[string]string) (*Permission, error) {
+	v := &validator{object: "template " + t.Name}
+	args := templateArgs{}
+
+	known := map[string]bool{}
+	for _, p := range t.Params {
+		known[p.Name] = true
+
+		value, ok := params[p.Name]
+		if !ok || strings.TrimSpace(value) == "" {
+			if p.Required {
+				v.add(p.Name, "is required")
+				continue
+			}
+			value = p.Default
+		}
+		args[p.Name] = value
+
+		if p.Name == "bucket" || p.Name == "buckets" {
+			for _, b := range args.list(p.Name) {
+				if !bucketNameRe.MatchString(b) {
+					v.add(p.Name, fmt.Sprintf("%q is not a valid bucket name", b))
+				}
+			}
+		}
+		if p.Name == "prefix" && strings.ContainsAny(value, "*?$") {
+			v.add(p.Name, "must not contain wildcards or variables")
+		}
+	}
+
+	for _, k := range sortedKeys(params) {
+		if !known[k] {
+			v.add(k, "is not a parameter of the template")
+		}
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	doc, err := t.build(args)
+	if err != nil {
+		return nil, &ValidationError{Object: v.object,
+			Fields: []*FieldError{{Field: "params", Message: err.Error()}}}
+	}
+
+	desc := []string{"template=" + t.Name}
+	for _, p := range t.Params {
+		desc = append(desc, p.Name+"="+args[p.Name])
+	}
+
+	perm := &Permission{
+		Name:        name,
+		Description: strings.Join(desc, "; "),
+	}
+
+	if err = perm.SetPolicyDocument(doc); err != nil {
+		return nil, err
+	}
+
+	if err = perm.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err = LintPermission(perm).Err(SeverityError); err != nil {
+		return nil, err
+	}
+
+	return perm, nil
+}
@@ -0,0 +1,97 @@
+package lyveapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPolicyTemplatesRender(t *testing.T) {
+	t.Parallel()
+
+	params := map[string]map[string]string{
+		"read-only-prefix":     {"bucket": "alpha", "prefix": "reports/"},
+		"write-only-dropbox":   {"bucket": "alpha", "prefix": "incoming/"},
+		"deny-delete":          {"bucket": "alpha"},
+		"tenant-isolation":     {"bucket": "shared"},
+		"source-ip-restricted": {"buckets": "alpha, beta", "cidrs": "10.0.0.0/8"},
+	}
+
+	templates := PolicyTemplates()
+	if len(templates) != len(params) {
+		t.Fatalf("Expected %d templates; got %d", len(params), len(templates))
+	}
+
+	for _, tmpl := range templates {
+		perm, err := tmpl.Render("perm-"+tmpl.Name, params[tmpl.Name])
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tmpl.Name, err)
+			continue
+		}
+
+		if perm.Type != Policy || perm.Name != "perm-"+tmpl.Name {
+			t.Errorf("%s: unexpected permission: %+v", tmpl.Name, perm)
+		}
+	}
+}
+
+func TestRenderPolicyTemplate(t *testing.T) {
+	t.Parallel()
+
+	perm, err := RenderPolicyTemplate("read-only-prefix", "reports",
+		map[string]string{"bucket": "alpha", "prefix": "reports/"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "template=read-only-prefix; bucket=alpha; prefix=reports/"
+	if perm.Description != expected {
+		t.Errorf("Unexpected description: %q", perm.Description)
+	}
+
+	d, _ := Evaluate([]Permission{*perm}, &AccessRequest{
+		Action: "s3:GetObject", Bucket: "alpha", Key: "reports/q1.csv"})
+	if !d.Allowed {
+		t.Errorf("Expected reads beneath the prefix to be allowed: %s", d.Reason)
+	}
+
+	d, _ = Evaluate([]Permission{*perm}, &AccessRequest{
+		Action: "s3:GetObject", Bucket: "alpha", Key: "private/key"})
+	if d.Allowed {
+		t.Error("Expected reads outside the prefix to be denied")
+	}
+
+	perm, err = RenderPolicyTemplate("tenant-isolation", "tenants",
+		map[string]string{"bucket": "shared", "variable": "aws:userid"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	d, _ = Evaluate([]Permission{*perm}, &AccessRequest{
+		Action: "s3:PutObject", Bucket: "shared", Key: "u1/file",
+		Context: map[string][]string{"aws:userid": {"u1"}}})
+	if !d.Allowed {
+		t.Errorf("Expected the tenant to write its prefix: %s", d.Reason)
+	}
+}
+
+func TestRenderPolicyTemplateErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := RenderPolicyTemplate("no-such-template", "x", nil)
+	if !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("Expected ErrUnknownTemplate; got %v", err)
+	}
+
+	_, err = RenderPolicyTemplate("read-only-prefix", "x",
+		map[string]string{"bucket": "Not_A_Bucket", "colour": "blue"})
+	var vErr *ValidationError
+	if !errors.As(err, &vErr) || len(vErr.Fields) != 3 {
+		t.Errorf("Expected three field errors; got %v", err)
+	}
+
+	_, err = RenderPolicyTemplate("source-ip-restricted", "x",
+		map[string]string{"buckets": "alpha", "cidrs": "10.0.0.0/33"})
+	if !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("Expected a validation error for a bad CIDR; got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package lyveapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Timestamp is a point in time as exchanged with the API, which uses RFC 3339
+// timestamps with or without fractional seconds. An empty Timestamp means the
+// time is not set, for example a service account which never expires.
+//
+// The value is kept exactly as received, so that it is marshalled back
+// unchanged, and is only parsed by Time. Malformed values therefore do not
+// prevent a response from being decoded; they are reported by Time instead.
+type Timestamp string
+
+// NewTimestamp returns the Timestamp for t, in UTC.
+func NewTimestamp(t time.Time) Timestamp {
+	if t.IsZero() {
+		return ""
+	}
+	return Timestamp(t.UTC().Format(time.RFC3339Nano))
+}
+
+// Time parses the timestamp. An empty timestamp is the zero time.
+func (ts Timestamp) Time() (time.Time, error) {
+	if ts == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, string(ts))
+}
+
+// IsZero returns true when the timestamp is not set.
+func (ts Timestamp) IsZero() bool {
+	return ts == ""
+}
+
+// Equal reports whether two timestamps denote the same instant, regardless of
+// their formatting. Timestamps which cannot be parsed are compared as
+// strings.
+func (ts Timestamp) Equal(other Timestamp) bool {
+	a, errA := ts.Time()
+	b, errB := other.Time()
+	if errA != nil || errB != nil {
+		return ts == other
+	}
+	return a.Equal(b)
+}
+
+func (ts Timestamp) String() string {
+	return string(ts)
+}
+
+// UnmarshalJSON decodes a JSON string, treating null as an empty timestamp.
+func (ts *Timestamp) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*ts = ""
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*ts = Timestamp(s)
+	return nil
+}
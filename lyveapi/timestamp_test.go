@@ -0,0 +1,62 @@
+package lyveapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestampJSON(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input    string
+		expected time.Time
+		output   string
+	}{
+		{`{"createTime":"2023-04-05T06:07:08Z"}`,
+			time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+			`{"createTime":"2023-04-05T06:07:08Z"}`},
+		{`{"createTime":"2023-04-05T06:07:08.123456+02:00"}`,
+			time.Date(2023, 4, 5, 4, 7, 8, 123456000, time.UTC),
+			`{"createTime":"2023-04-05T06:07:08.123456+02:00"}`},
+		{`{"createTime":""}`, time.Time{}, `{}`},
+		{`{"createTime":null}`, time.Time{}, `{}`},
+	}
+
+	for _, test := range tests {
+		var p Permission
+		if err := json.Unmarshal([]byte(test.input), &p); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.input, err)
+			continue
+		}
+
+		got, err := p.CreateTime.Time()
+		if err != nil || !got.Equal(test.expected) {
+			t.Errorf("%s: unexpected time %v, %v", test.input, got, err)
+		}
+
+		b, _ := json.Marshal(&p)
+		if string(b) != test.output {
+			t.Errorf("%s: expected %s; got %s", test.input, test.output, b)
+		}
+	}
+
+	var p Permission
+	if err := json.Unmarshal([]byte(`{"createTime":42}`), &p); err == nil {
+		t.Error("Expected a non-string timestamp to be rejected")
+	}
+}
+
+func TestTimestampEqual(t *testing.T) {
+	t.Parallel()
+
+	a := Timestamp("2023-04-05T06:07:08Z")
+	b := Timestamp("2023-04-05T08:07:08.000+02:00")
+	if !a.Equal(b) || a.Equal("") || !Timestamp("x").Equal("x") {
+		t.Error("Unexpected comparison result")
+	}
+	if NewTimestamp(time.Time{}) != "" || !NewTimestamp(time.Time{}).IsZero() {
+		t.Error("Expected the zero time to be an empty timestamp")
+	}
+}
@@ -0,0 +1,334 @@
+package lyveapi
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrTxnCommitted is returned when a transaction is committed twice.
+	ErrTxnCommitted = errors.New("transaction was already committed")
+	// ErrRollbackIncomplete is matched by errors.Is when a transaction failed
+	// and one or more of its applied steps could not be rolled back.
+	ErrRollbackIncomplete = errors.New("transaction rollback incomplete")
+)
+
+// TxnStepStatus is the state of a step of a transaction.
+type TxnStepStatus string
+
+const (
+	// TxnStepPending means the step was not run, because an earlier step
+	// failed.
+	TxnStepPending TxnStepStatus = "pending"
+	// TxnStepApplied means the step succeeded and remains in effect.
+	TxnStepApplied TxnStepStatus = "applied"
+	// TxnStepFailed means the step itself failed.
+	TxnStepFailed TxnStepStatus = "failed"
+	// TxnStepRolledBack means the step succeeded and was later undone.
+	TxnStepRolledBack TxnStepStatus = "rolled-back"
+	// TxnStepRollbackFailed means the step succeeded, but undoing it failed,
+	// so its effect remains.
+	TxnStepRollbackFailed TxnStepStatus = "rollback-failed"
+)
+
+// TxnStepReport describes the outcome of a step of a transaction.
+type TxnStepReport struct {
+	// Op is the operation, such as "CreatePermission".
+	Op string
+	// Target describes the object the step applies to.
+	Target string
+	// Id is the ID of the object the step applied to, if known.
+	Id string
+	// Status is the outcome of the step.
+	Status TxnStepStatus
+	// Err is the error of a failed step, or of a failed rollback.
+	Err error
+}
+
+// TxnError is returned by Txn.Commit when a step fails. The applied steps are
+// rolled back before it is returned.
+type TxnError struct {
+	// Step is the index of the step which failed.
+	Step int
+	// Op is the operation of the step which failed.
+	Op string
+	// Err is the error of the step which failed.
+	Err error
+	// RollbackFailures lists the steps which could not be rolled back, and
+	// whose effects therefore remain.
+	RollbackFailures []TxnStepReport
+}
+
+func (e *TxnError) Error() string {
+	msg := "transaction step " + strconv.Itoa(e.Step) + " (" + e.Op +
+		") failed: " + e.Err.Error()
+	if len(e.RollbackFailures) == 0 {
+		return msg + "; all applied steps were rolled back"
+	}
+
+	failures := make([]string, len(e.RollbackFailures))
+	for i, f := range e.RollbackFailures {
+		failures[i] = f.Op + " " + f.Target + ": " + f.Err.Error()
+	}
+	return msg + "; rollback failed for: " + strings.Join(failures, "; ")
+}
+
+func (e *TxnError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrRollbackIncomplete and some steps could not
+// be rolled back.
+func (e *TxnError) Is(target error) bool {
+	return target == ErrRollbackIncomplete && len(e.RollbackFailures) > 0
+}
+
+// TxnRef refers to an object created by a transaction, whose ID is only known
+// once the transaction has run.
+type TxnRef struct {
+	id    string
+	creds *CreateServiceAcctResp
+}
+
+// Id returns the ID of the created object, or an empty string if it was not
+// created or was deleted by a rollback. An object whose deletion failed keeps
+// its ID, so that it can be cleaned up.
+func (r *TxnRef) Id() string {
+	return r.id
+}
+
+// Credentials returns the credentials of a created service account, or nil
+// if the reference is not to a service account, or it was not created or was
+// deleted by a rollback.
+func (r *TxnRef) Credentials() *CreateServiceAcctResp {
+	return r.creds
+}
+
+type txnStep struct {
+	report TxnStepReport
+	apply  func() error
+	// undo is set by apply when there is something to roll back.
+	undo func() error
+}
+
+// Txn queues changes which are applied in order by Commit. If any change
+// fails, the changes already applied are undone with compensating actions, in
+// reverse order: created objects are deleted and updated objects are restored
+// to the definitions captured before they were updated. The API has no
+// transactions, so other clients may observe the intermediate states.
+//
+// Deletions are not offered, since they cannot be undone faithfully: a
+// recreated permission has a new ID and a recreated service account new
+// credentials.
+type Txn struct {
+	client    *Client
+	steps     []*txnStep
+	committed bool
+}
+
+// NewTxn returns an empty transaction.
+func (client *Client) NewTxn() *Txn {
+	return &Txn{client: client}
+}
+
+func (t *Txn) add(op, target string, apply func(s *txnStep) error) {
+	s := &txnStep{report: TxnStepReport{
+		Op: op, Target: target, Status: TxnStepPending}}
+	s.apply = func() error { return apply(s) }
+	t.steps = append(t.steps, s)
+}
+
+// CreatePermission queues the creation of a permission. The returned
+// reference supplies its ID once the transaction has run, and may be passed
+// to CreateServiceAccount. On rollback the permission is deleted.
+func (t *Txn) CreatePermission(p *Permission) *TxnRef {
+	ref := &TxnRef{}
+	t.add("CreatePermission", strconv.Quote(p.Name), func(s *txnStep) error {
+		created, err := t.client.CreatePermission(p)
+		if err != nil {
+			return err
+		}
+
+		ref.id, s.report.Id = created.Id, created.Id
+		s.undo = func() error {
+			if err := t.client.DeletePermission(created.Id); err != nil {
+				return err
+			}
+			ref.id = ""
+			return nil
+		}
+		return nil
+	})
+	return ref
+}
+
+// UpdatePermission queues an update of the permission with the given ID. Its
+// definition is captured before it is updated, and restored on rollback.
+func (t *Txn) UpdatePermission(permissionId string, p *Permission) {
+	t.add("UpdatePermission", permissionId, func(s *txnStep) error {
+		s.report.Id = permissionId
+		previous, err := t.client.GetPermission(permissionId)
+		if err != nil {
+			return err
+		}
+
+		if err = t.client.UpdatePermission(permissionId, p); err != nil {
+			return err
+		}
+
+		s.undo = func() error {
+			return t.client.UpdatePermission(permissionId, previous)
+		}
+		return nil
+	})
+}
+
+// CreateServiceAccount queues the creation of a service account. The IDs of
+// the permissions referred to by refs, which must be created earlier in the
+// transaction, are added to the permissions of the request when the step
+// runs. The returned reference supplies the ID and credentials of the account
+// once the transaction has run. On rollback the account is deleted.
+func (t *Txn) CreateServiceAccount(
+	req *CreateServiceAcctReq, refs ...*TxnRef) *TxnRef {
+	ref := &TxnRef{}
+	t.add("CreateServiceAccount", strconv.Quote(req.Name),
+		func(s *txnStep) error {
+			r := *req
+			r.Permissions = append([]string(nil), req.Permissions...)
+			for i, pr := range refs {
+				if pr.id == "" {
+					return fmt.Errorf("permission reference %d has no ID; "+
+						"it must be created earlier in the transaction", i)
+				}
+				r.Permissions = append(r.Permissions, pr.id)
+			}
+
+			created, err := t.client.CreateServiceAccount(&r)
+			if err != nil {
+				return err
+			}
+
+			ref.id, ref.creds, s.report.Id = created.Id, created, created.Id
+			s.undo = func() error {
+				if err := t.client.DeleteServiceAccount(created.Id); err != nil {
+					return err
+				}
+				ref.id, ref.creds = "", nil
+				return nil
+			}
+			return nil
+		})
+	return ref
+}
+
+// UpdateServiceAccount queues an update of the service account with the
+// given ID. Its definition is captured before it is updated, and restored on
+// rollback.
+func (t *Txn) UpdateServiceAccount(svcAcctId string, acct *ServiceAcct) {
+	t.add("UpdateServiceAccount", svcAcctId, func(s *txnStep) error {
+		s.report.Id = svcAcctId
+		previous, err := t.client.GetServiceAccount(svcAcctId)
+		if err != nil {
+			return err
+		}
+
+		if err = t.client.UpdateServiceAccount(svcAcctId, acct); err != nil {
+			return err
+		}
+
+		s.undo = func() error {
+			return t.client.UpdateServiceAccount(svcAcctId, previous)
+		}
+		return nil
+	})
+}
+
+// EnableServiceAccount queues enabling the service account with the given ID.
+// On rollback the account is disabled again, if it was disabled before.
+func (t *Txn) EnableServiceAccount(svcAcctId string) {
+	t.setServiceAccountEnabled("EnableServiceAccount", svcAcctId, true)
+}
+
+// DisableServiceAccount queues disabling the service account with the given
+// ID. On rollback the account is enabled again, if it was enabled before.
+func (t *Txn) DisableServiceAccount(svcAcctId string) {
+	t.setServiceAccountEnabled("DisableServiceAccount", svcAcctId, false)
+}
+
+func (t *Txn) setServiceAccountEnabled(op, svcAcctId string, enable bool) {
+	set := func(enable bool) error {
+		if enable {
+			return t.client.EnableServiceAccount(svcAcctId)
+		}
+		return t.client.DisableServiceAccount(svcAcctId)
+	}
+
+	t.add(op, svcAcctId, func(s *txnStep) error {
+		s.report.Id = svcAcctId
+		previous, err := t.client.GetServiceAccount(svcAcctId)
+		if err != nil {
+			return err
+		}
+
+		if err = set(enable); err != nil {
+			return err
+		}
+
+		if previous.Enabled != enable {
+			s.undo = func() error { return set(previous.Enabled) }
+		}
+		return nil
+	})
+}
+
+// Commit runs the queued steps in order. If a step fails, the steps already
+// applied are rolled back in reverse order and a *TxnError is returned, which
+// also matches ErrRollbackIncomplete when some steps could not be rolled
+// back. The report lists the outcome of every step in either case. A
+// transaction can only be committed once.
+func (t *Txn) Commit() ([]TxnStepReport, error) {
+	if t.committed {
+		return nil, ErrTxnCommitted
+	}
+	t.committed = true
+
+	var txnErr *TxnError
+	for i, s := range t.steps {
+		if err := s.apply(); err != nil {
+			s.report.Status, s.report.Err = TxnStepFailed, err
+			txnErr = &TxnError{Step: i, Op: s.report.Op, Err: err}
+			break
+		}
+		s.report.Status = TxnStepApplied
+	}
+
+	if txnErr != nil {
+		for i := txnErr.Step - 1; i >= 0; i-- {
+			s := t.steps[i]
+			if s.undo == nil {
+				s.report.Status = TxnStepRolledBack
+				continue
+			}
+
+			if err := s.undo(); err != nil {
+				s.report.Status, s.report.Err = TxnStepRollbackFailed, err
+				txnErr.RollbackFailures = append(txnErr.RollbackFailures,
+					s.report)
+				continue
+			}
+			s.report.Status = TxnStepRolledBack
+		}
+	}
+
+	reports := make([]TxnStepReport, len(t.steps))
+	for i, s := range t.steps {
+		reports[i] = s.report
+	}
+
+	if txnErr != nil {
+		return reports, txnErr
+	}
+	return reports, nil
+}
@@ -0,0 +1,128 @@
+package lyveapi
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestTxnCommit(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+
+	txn := client.NewTxn()
+	ref := txn.CreatePermission(&Permission{
+		Name: "logs", Type: BucketPrefix, Prefix: "logs-", Actions: ReadOnly})
+	acct := txn.CreateServiceAccount(&CreateServiceAcctReq{
+		Name: "a", Permissions: []string{p}}, ref)
+	txn.UpdatePermission(p, &Permission{
+		Name: "p", Type: AllBuckets, Actions: AllOperations})
+
+	reports, err := txn.Commit()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, r := range reports {
+		if r.Status != TxnStepApplied {
+			t.Errorf("Unexpected report: %+v", r)
+		}
+	}
+
+	stored := f.serviceAccount(acct.Id())
+	if stored == nil || acct.Credentials() == nil ||
+		!reflect.DeepEqual(stored.Permissions, []string{p, ref.Id()}) {
+		t.Errorf("Unexpected service account: %+v", stored)
+	}
+	if f.permission(p).Actions != AllOperations {
+		t.Errorf("Expected the permission to be updated")
+	}
+
+	if _, err = txn.Commit(); !errors.Is(err, ErrTxnCommitted) {
+		t.Errorf("Expected ErrTxnCommitted; got %v", err)
+	}
+}
+
+func TestTxnRollback(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+	a := f.addServiceAccount(ServiceAcct{
+		Name: "a", Enabled: true, Permissions: []string{p}})
+
+	txn := client.NewTxn()
+	ref := txn.CreatePermission(&Permission{
+		Name: "logs", Type: AllBuckets, Actions: ReadOnly})
+	txn.UpdatePermission(p, &Permission{
+		Name: "p", Type: AllBuckets, Actions: AllOperations})
+	txn.DisableServiceAccount(a)
+	acct := txn.CreateServiceAccount(&CreateServiceAcctReq{Name: "b"}, ref)
+	txn.UpdatePermission("missing", &Permission{
+		Name: "x", Type: AllBuckets, Actions: ReadOnly})
+	txn.DisableServiceAccount(a)
+
+	reports, err := txn.Commit()
+	var txnErr *TxnError
+	if !errors.As(err, &txnErr) || txnErr.Step != 4 || !IsNotFound(err) ||
+		errors.Is(err, ErrRollbackIncomplete) {
+		t.Fatalf("Expected step 4 to fail; got %v", err)
+	}
+
+	expected := []TxnStepStatus{TxnStepRolledBack, TxnStepRolledBack,
+		TxnStepRolledBack, TxnStepRolledBack, TxnStepFailed, TxnStepPending}
+	for i, r := range reports {
+		if r.Status != expected[i] {
+			t.Errorf("Step %d: expected %s; got %s", i, expected[i], r.Status)
+		}
+	}
+
+	if f.permission(reports[0].Id) != nil ||
+		f.serviceAccount(reports[3].Id) != nil {
+		t.Error("Expected the created objects to be deleted")
+	}
+	if ref.Id() != "" || acct.Id() != "" || acct.Credentials() != nil {
+		t.Error("Expected the references to deleted objects to be cleared")
+	}
+	if f.permission(p).Actions != ReadOnly || !f.serviceAccount(a).Enabled {
+		t.Error("Expected the previous definitions to be restored")
+	}
+}
+
+func TestTxnRollbackIncomplete(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+
+	txn := client.NewTxn()
+	ref := txn.CreatePermission(&Permission{
+		Name: "logs", Type: AllBuckets, Actions: ReadOnly})
+	txn.CreateServiceAccount(&CreateServiceAcctReq{
+		Name: "a", Permissions: []string{"missing"}})
+
+	f.mtx.Lock()
+	f.before = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodDelete {
+			fakeError(w, http.StatusInternalServerError, "InternalError")
+			return false
+		}
+		return true
+	}
+	f.mtx.Unlock()
+
+	reports, err := txn.Commit()
+	var txnErr *TxnError
+	if !errors.As(err, &txnErr) || !errors.Is(err, ErrRollbackIncomplete) ||
+		len(txnErr.RollbackFailures) != 1 ||
+		txnErr.RollbackFailures[0].Id != ref.Id() {
+		t.Fatalf("Expected an incomplete rollback; got %v", err)
+	}
+	if reports[0].Status != TxnStepRollbackFailed || reports[0].Err == nil {
+		t.Errorf("Unexpected report: %+v", reports[0])
+	}
+	if f.permission(ref.Id()) == nil {
+		t.Error("Expected the permission to remain")
+	}
+}
@@ -17,31 +17,36 @@ type CreateServiceAcctReq struct {
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
 	Permissions []string `json:"permissions"`
+	// ExpirationDate is when the service account expires; see
+	// SetExpiration. If empty, the account never expires.
+	ExpirationDate Timestamp `json:"expirationDate,omitempty"`
 }
 
 type CreateServiceAcctResp struct {
-	Id             string `json:"id"`
-	AccessKey      string `json:"accessKey"`
-	Secret         string `json:"secret"`
-	ExpirationDate string `json:"expirationDate"`
+	Id             string    `json:"id"`
+	AccessKey      string    `json:"accessKey"`
+	Secret         string    `json:"secret"`
+	ExpirationDate Timestamp `json:"expirationDate"`
 }
 
 // Depending on the API used, some of these fields may or may not be used.
 type ServiceAcct struct {
-	Id             string   `json:"id"`
-	Name           string   `json:"name"`
-	Description    string   `json:"description"`
-	Enabled        bool     `json:"enabled"`
-	ExpirationDate string   `json:"expirationDate"`
-	ReadyState     bool     `json:"readyState"`
-	Permissions    []string `json:"permissions,omitempty"`
-}
-
-// type ServiceAcctUpdateReq struct {
-// 	Name        string   `json:"name"`
-// 	Description string   `json:"description,omitempty"`
-// 	Permissions []string `json:"permissions,omitempty"`
-// }
+	Id             string    `json:"id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	Enabled        bool      `json:"enabled"`
+	ExpirationDate Timestamp `json:"expirationDate"`
+	ReadyState     bool      `json:"readyState"`
+	Permissions    []string  `json:"permissions,omitempty"`
+}
+
+// ServiceAcctUpdateReq describes a partial update of a service account. Only
+// the fields which are not nil are changed; see UpdateServiceAccountFields.
+type ServiceAcctUpdateReq struct {
+	Name        *string   `json:"name,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Permissions *[]string `json:"permissions,omitempty"`
+}
 
 type ServiceAcctList []ServiceAcct
 
@@ -55,7 +60,19 @@ type Permission struct {
 	Prefix      string         `json:"prefix,omitempty"`
 	Buckets     []string       `json:"buckets,omitempty"`
 	Policy      string         `json:"policy,omitempty"`
-	CreateTime  string         `json:"createTime,omitempty"`
+	CreateTime  Timestamp      `json:"createTime,omitempty"`
+}
+
+// PermissionUpdateReq describes a partial update of a permission. Only the
+// fields which are not nil are changed; see UpdatePermissionFields.
+type PermissionUpdateReq struct {
+	Name        *string         `json:"name,omitempty"`
+	Description *string         `json:"description,omitempty"`
+	Type        *PermissionType `json:"type,omitempty"`
+	Actions     *Action         `json:"actions,omitempty"`
+	Prefix      *string         `json:"prefix,omitempty"`
+	Buckets     *[]string       `json:"buckets,omitempty"`
+	Policy      *string         `json:"policy,omitempty"`
 }
 
 func (p *Permission) IsPolicyPermission() bool {
@@ -117,7 +134,7 @@ type SubAccount struct {
 	// SubAccountId is the unique identifier of the given sub-account
 	SubAccountId string `json:"subAccountId"`
 	// CreateTime is the timestamp of this bucket's creation
-	CreateTime string `json:"createTime"`
+	CreateTime Timestamp `json:"createTime"`
 	// UsageGB is the amount of GBs consumed by the given sub-account
 	UsageGB float64 `json:"usageGB,omitempty"`
 	// Users is the number of users tied to the given sub-account
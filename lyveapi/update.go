@@ -0,0 +1,164 @@
+package lyveapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// Ptr returns a pointer to v, which is convenient for setting the fields of
+// ServiceAcctUpdateReq and PermissionUpdateReq, for example:
+//
+//	req := &PermissionUpdateReq{Description: Ptr("read-only access to logs")}
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// Empty returns true when the request changes nothing.
+func (r *ServiceAcctUpdateReq) Empty() bool {
+	return r.Name == nil && r.Description == nil && r.Permissions == nil
+}
+
+// ApplyTo changes the fields of acct which are set in the request.
+func (r *ServiceAcctUpdateReq) ApplyTo(acct *ServiceAcct) {
+	if r.Name != nil {
+		acct.Name = *r.Name
+	}
+	if r.Description != nil {
+		acct.Description = *r.Description
+	}
+	if r.Permissions != nil {
+		acct.Permissions = append([]string(nil), *r.Permissions...)
+	}
+}
+
+// Empty returns true when the request changes nothing.
+func (r *PermissionUpdateReq) Empty() bool {
+	return r.Name == nil && r.Description == nil && r.Type == nil &&
+		r.Actions == nil && r.Prefix == nil && r.Buckets == nil &&
+		r.Policy == nil
+}
+
+// ApplyTo changes the fields of p which are set in the request. When the
+// request changes the type of the permission, fields which do not apply to
+// the new type are cleared unless the request sets them, for example the
+// Policy of a permission changed from type "policy" to "bucket-names".
+func (r *PermissionUpdateReq) ApplyTo(p *Permission) {
+	if r.Type != nil && *r.Type != p.Type {
+		p.Type = *r.Type
+		if p.Type != BucketPrefix {
+			p.Prefix = ""
+		}
+		if p.Type != BucketNames {
+			p.Buckets = nil
+		}
+		if p.Type == Policy {
+			p.Actions = ""
+		} else {
+			p.Policy = ""
+		}
+	}
+
+	if r.Name != nil {
+		p.Name = *r.Name
+	}
+	if r.Description != nil {
+		p.Description = *r.Description
+	}
+	if r.Actions != nil {
+		p.Actions = *r.Actions
+	}
+	if r.Prefix != nil {
+		p.Prefix = *r.Prefix
+	}
+	if r.Buckets != nil {
+		p.Buckets = append([]string(nil), *r.Buckets...)
+	}
+	if r.Policy != nil {
+		p.Policy = *r.Policy
+	}
+}
+
+// UpdateServiceAccountFields changes only the fields of the service account
+// which are set in updateReq, unlike UpdateServiceAccount which replaces the
+// whole account. Only the fields set in the request are sent to the API, so
+// fields such as Enabled are never cleared by accident, nor are changes made
+// concurrently to other fields overwritten. The updated account is returned.
+// If the request changes nothing, the account is returned without being
+// written.
+func (client *Client) UpdateServiceAccountFields(svcAcctId string,
+	updateReq *ServiceAcctUpdateReq) (*ServiceAcct, error) {
+	const op = "UpdateServiceAccountFields"
+
+	client.mtx.RLock()
+	url := client.apiUrl + "/service-accounts/" + svcAcctId
+	token := client.token
+	client.mtx.RUnlock()
+
+	var buf []byte
+	var err error
+	var rdr io.ReadCloser
+
+	if err = updateReq.Validate(); err != nil {
+		return nil, opError(op, http.MethodPut, url, err)
+	}
+
+	if !updateReq.Empty() {
+		if buf, err = json.Marshal(updateReq); err != nil {
+			return nil, opError(op, http.MethodPut, url, err)
+		}
+
+		if rdr, err = apiRequestAuthenticated(
+			op, token, http.MethodPut, url, buf); err != nil {
+			return nil, err
+		}
+
+		if rdr != nil {
+			rdr.Close()
+		}
+	}
+
+	return client.GetServiceAccount(svcAcctId)
+}
+
+// UpdatePermissionFields changes only the fields of the permission which are
+// set in updateReq, unlike UpdatePermission which replaces the whole
+// permission. The request is validated and only the fields set in it are sent
+// to the API. When the request changes the type of the permission, the API
+// clears fields which do not apply to the new type, as described by
+// PermissionUpdateReq.ApplyTo. The updated permission is returned. If the
+// request changes nothing, the permission is returned without being written.
+func (client *Client) UpdatePermissionFields(permissionId string,
+	updateReq *PermissionUpdateReq) (*Permission, error) {
+	const op = "UpdatePermissionFields"
+
+	client.mtx.RLock()
+	url := client.apiUrl + "/permissions/" + permissionId
+	token := client.token
+	client.mtx.RUnlock()
+
+	var buf []byte
+	var err error
+	var rdr io.ReadCloser
+
+	if err = updateReq.Validate(); err != nil {
+		return nil, opError(op, http.MethodPut, url, err)
+	}
+
+	if !updateReq.Empty() {
+		if buf, err = json.Marshal(updateReq); err != nil {
+			return nil, opError(op, http.MethodPut, url, err)
+		}
+
+		if rdr, err = apiRequestAuthenticated(
+			op, token, http.MethodPut, url, buf); err != nil {
+			return nil, err
+		}
+
+		if rdr != nil {
+			rdr.Close()
+		}
+	}
+
+	return client.GetPermission(permissionId)
+}
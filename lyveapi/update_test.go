@@ -0,0 +1,200 @@
+package lyveapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServiceAcctUpdateReqJSON(t *testing.T) {
+	t.Parallel()
+
+	b, err := json.Marshal(&ServiceAcctUpdateReq{Description: Ptr("")})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(b) != `{"description":""}` {
+		t.Errorf("Expected only the set field to be encoded: %s", b)
+	}
+}
+
+func TestPermissionUpdateReqApplyTo(t *testing.T) {
+	t.Parallel()
+
+	p := &Permission{
+		Name:   "p",
+		Type:   Policy,
+		Policy: `{"Statement":[]}`,
+	}
+
+	(&PermissionUpdateReq{
+		Type:    Ptr(BucketNames),
+		Actions: Ptr(ReadOnly),
+		Buckets: &[]string{"alpha"},
+	}).ApplyTo(p)
+
+	expected := &Permission{
+		Name:    "p",
+		Type:    BucketNames,
+		Actions: ReadOnly,
+		Buckets: []string{"alpha"},
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Errorf("Unexpected result: %+v", p)
+	}
+}
+
+func TestUpdateFields(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{
+		Name: "p", Description: "old", Type: BucketPrefix, Prefix: "logs-",
+		Actions: ReadOnly})
+	a := f.addServiceAccount(ServiceAcct{
+		Name: "a", Description: "old", Enabled: true, Permissions: []string{p}})
+
+	acct, err := client.UpdateServiceAccountFields(a,
+		&ServiceAcctUpdateReq{Description: Ptr("new")})
+	if err != nil || acct.Description != "new" {
+		t.Fatalf("Unexpected result: %+v, %v", acct, err)
+	}
+
+	stored := f.serviceAccount(a)
+	if !stored.Enabled || stored.Name != "a" ||
+		!reflect.DeepEqual(stored.Permissions, []string{p}) {
+		t.Errorf("Expected other fields to be kept: %+v", stored)
+	}
+
+	_, err = client.UpdateServiceAccountFields(a,
+		&ServiceAcctUpdateReq{Permissions: &[]string{}})
+	if !errors.Is(err, ErrNoPermissionsProvided) {
+		t.Errorf("Expected a validation error; got %v", err)
+	}
+
+	perm, err := client.UpdatePermissionFields(p,
+		&PermissionUpdateReq{Actions: Ptr(AllOperations)})
+	if err != nil || perm.Actions != AllOperations || perm.Prefix != "logs-" {
+		t.Fatalf("Unexpected result: %+v, %v", perm, err)
+	}
+	if stored := f.permission(p); stored.Description != "old" ||
+		stored.Actions != AllOperations {
+		t.Errorf("Unexpected stored permission: %+v", stored)
+	}
+
+	puts := f.count(http.MethodPut, "/permissions")
+	if _, err = client.UpdatePermissionFields(p, &PermissionUpdateReq{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := f.count(http.MethodPut, "/permissions"); n != puts {
+		t.Error("Expected an empty request not to be written")
+	}
+}
+
+func TestUpdateFieldsSendsOnlySetFields(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{
+		Name: "p", Description: "old", Type: BucketPrefix, Prefix: "logs-",
+		Actions: ReadOnly})
+
+	// Another administrator renames the permission after it was last read,
+	// which must not be undone by the update.
+	var body string
+	f.mtx.Lock()
+	f.before = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodPut {
+			b, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(b))
+			body = string(b)
+			f.perms[p].Name = "renamed"
+		}
+		return true
+	}
+	f.mtx.Unlock()
+
+	perm, err := client.UpdatePermissionFields(p,
+		&PermissionUpdateReq{Description: Ptr("new")})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if body != `{"description":"new"}` {
+		t.Errorf("Expected only the set field to be sent: %s", body)
+	}
+	if perm.Name != "renamed" || perm.Description != "new" {
+		t.Errorf("Unexpected result: %+v", perm)
+	}
+
+	_, err = client.UpdatePermissionFields(p,
+		&PermissionUpdateReq{Name: Ptr(" ")})
+	var opErr *OperationError
+	if !errors.As(err, &opErr) || opErr.Op != "UpdatePermissionFields" ||
+		!strings.HasSuffix(opErr.URL, "/permissions/"+p) {
+		t.Errorf("Expected an operation error with the URL; got %v", err)
+	}
+}
+
+func TestPermissionUpdateReqValidate(t *testing.T) {
+	t.Parallel()
+
+	err := (&PermissionUpdateReq{
+		Type:    Ptr(AllBuckets),
+		Actions: Ptr(ReadOnly),
+		Prefix:  Ptr("logs-"),
+	}).Validate()
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) || len(valErr.Fields) != 1 ||
+		valErr.Fields[0].Field != "prefix" {
+		t.Errorf("Expected the prefix to be rejected; got %v", err)
+	}
+
+	err = (&PermissionUpdateReq{Type: Ptr(Policy)}).Validate()
+	if !errors.Is(err, PolicyMissingErr) {
+		t.Errorf("Expected the policy to be required; got %v", err)
+	}
+
+	if err = (&PermissionUpdateReq{Prefix: Ptr("logs-")}).Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestUpdatePermissionFieldsChangeType(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	p := f.addPermission(Permission{
+		Name: "p", Type: Policy, Policy: `{"Statement":[]}`})
+
+	_, err := client.UpdatePermissionFields(p, &PermissionUpdateReq{
+		Type: Ptr(BucketNames), Actions: Ptr(ReadOnly)})
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) || valErr.Fields[0].Field != "buckets" {
+		t.Fatalf("Expected the buckets to be required; got %v", err)
+	}
+
+	perm, err := client.UpdatePermissionFields(p, &PermissionUpdateReq{
+		Type: Ptr(BucketNames), Actions: Ptr(ReadOnly),
+		Buckets: &[]string{"alpha"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := &Permission{Id: p, Name: "p", Type: BucketNames,
+		ReadyState: true, Actions: ReadOnly, Buckets: []string{"alpha"}}
+	if !reflect.DeepEqual(perm, expected) {
+		t.Errorf("Expected the policy to be cleared: %+v", perm)
+	}
+	if stored := f.permission(p); !reflect.DeepEqual(stored, expected) {
+		t.Errorf("Unexpected stored permission: %+v", stored)
+	}
+	if n := f.count(http.MethodPut, "/permissions/"); n != 1 {
+		t.Errorf("Expected the invalid update not to be sent; got %d", n)
+	}
+}
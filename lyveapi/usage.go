@@ -1,7 +1,6 @@
 package lyveapi
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -63,6 +62,8 @@ func (client *Client) GetMonthlyUsage(
 	toMonth Month,
 	toYear uint,
 ) (*MonthlyUsageResp, error) {
+	const op = "GetMonthlyUsage"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/usage/monthly"
 	token := client.token
@@ -81,17 +82,15 @@ func (client *Client) GetMonthlyUsage(
 	url := endpoint + "?" + params.Encode()
 
 	if rdr, err = apiRequestAuthenticated(
-		token, http.MethodGet, url, nil); err != nil {
+		op, token, http.MethodGet, url, nil); err != nil {
 		return nil, err
 	}
 
 	defer rdr.Close()
 
-	respBodyDecoder := json.NewDecoder(rdr)
-
 	usageResp := &MonthlyUsageResp{}
 
-	if err := respBodyDecoder.Decode(usageResp); err != nil {
+	if err := decodeResponseBody(rdr, usageResp); err != nil {
 		return nil, err
 	}
 
@@ -103,6 +102,8 @@ func (client *Client) GetMonthlyUsage(
 // no information is returned in the UsageBySubAccount field, since sub-accounts
 // cannot have their own sub-accounts.
 func (client *Client) GetCurrentUsage() (*CurrentUsageResp, error) {
+	const op = "GetCurrentUsage"
+
 	client.mtx.RLock()
 	endpoint := client.apiUrl + "/usage/current"
 	token := client.token
@@ -112,17 +113,15 @@ func (client *Client) GetCurrentUsage() (*CurrentUsageResp, error) {
 	var rdr io.ReadCloser
 
 	if rdr, err = apiRequestAuthenticated(
-		token, http.MethodGet, endpoint, nil); err != nil {
+		op, token, http.MethodGet, endpoint, nil); err != nil {
 		return nil, err
 	}
 
 	defer rdr.Close()
 
-	respBodyDecoder := json.NewDecoder(rdr)
-
 	usageResp := &CurrentUsageResp{}
 
-	if err := respBodyDecoder.Decode(usageResp); err != nil {
+	if err := decodeResponseBody(rdr, usageResp); err != nil {
 		return nil, err
 	}
 
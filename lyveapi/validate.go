@@ -0,0 +1,376 @@
+package lyveapi
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrValidationFailed is matched by errors.Is for any *ValidationError, which
+// is returned when a request fails client-side validation before it is sent to
+// the API.
+var ErrValidationFailed = errors.New("request failed validation")
+
+// bucketNameRe describes valid S3 bucket names, which are between 3 and 63
+// characters long and consist of lowercase letters, numbers, dots and hyphens.
+var bucketNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// bucketPrefixRe describes valid prefixes of bucket names used with
+// bucket-prefix permissions.
+var bucketPrefixRe = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{0,62}$`)
+
+// FieldError describes a problem with a single field of a request.
+type FieldError struct {
+	// Field is the JSON name of the offending field.
+	Field string
+	// Message describes what is wrong with the field.
+	Message string
+	// Err is an optional sentinel error further classifying the problem,
+	// such as PolicyMissingErr.
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when one or more fields of a request are
+// invalid. All problems found are reported at once, rather than only the
+// first.
+type ValidationError struct {
+	// Object is the name of the validated type, such as "Permission".
+	Object string
+	// Fields lists each of the problems found.
+	Fields []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+
+	return "invalid " + e.Object + ": " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether target is ErrValidationFailed.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidationFailed
+}
+
+// Unwrap returns the field errors, making any sentinel errors they carry
+// reachable with errors.Is.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i, f := range e.Fields {
+		errs[i] = f
+	}
+	return errs
+}
+
+// validator accumulates field errors for a single object.
+type validator struct {
+	object string
+	fields []*FieldError
+}
+
+func (v *validator) add(field, message string) {
+	v.fields = append(v.fields, &FieldError{Field: field, Message: message})
+}
+
+func (v *validator) addErr(field, message string, err error) {
+	v.fields = append(v.fields,
+		&FieldError{Field: field, Message: message, Err: err})
+}
+
+// err returns a *ValidationError if any problems were recorded, otherwise nil.
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Object: v.object, Fields: v.fields}
+}
+
+// checkIds validates a list of identifiers, which must be non-empty and must
+// not repeat.
+func (v *validator) checkIds(field string, ids []string) {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		switch {
+		case strings.TrimSpace(id) == "":
+			v.add(field, "must not contain empty IDs")
+		case seen[id]:
+			v.add(field, "contains duplicate ID "+id)
+		}
+		seen[id] = true
+	}
+}
+
+// checkBuckets validates a list of bucket names, which must be well-formed and
+// must not repeat.
+func (v *validator) checkBuckets(buckets []string) {
+	seen := make(map[string]bool, len(buckets))
+	for _, b := range buckets {
+		if !bucketNameRe.MatchString(b) {
+			v.add("buckets", "invalid bucket name "+strconv.Quote(b))
+		} else if seen[b] {
+			v.add("buckets", "contains duplicate bucket "+b)
+		}
+		seen[b] = true
+	}
+}
+
+// checkTypeFields rejects the fields which are set but do not apply to
+// permissions of type t: only policy permissions have a policy, and they have
+// no actions, only bucket-prefix permissions have a prefix, and only
+// bucket-names permissions have buckets.
+func (v *validator) checkTypeFields(t PermissionType,
+	actions, prefix, buckets, policy bool) {
+	if !t.Valid() {
+		return
+	}
+	if actions && t == Policy {
+		v.add("actions", "is not used by "+string(t)+" permissions")
+	}
+	if prefix && t != BucketPrefix {
+		v.add("prefix", "is not used by "+string(t)+" permissions")
+	}
+	if buckets && t != BucketNames {
+		v.add("buckets", "is not used by "+string(t)+" permissions")
+	}
+	if policy && t != Policy {
+		v.add("policy", "is not used by "+string(t)+" permissions")
+	}
+}
+
+// Valid returns true when t is one of the permission types known to the API.
+func (t PermissionType) Valid() bool {
+	switch t {
+	case AllBuckets, BucketPrefix, BucketNames, Policy:
+		return true
+	}
+	return false
+}
+
+// Valid returns true when a is one of the actions known to the API.
+func (a Action) Valid() bool {
+	switch a {
+	case AllOperations, ReadOnly, WriteOnly:
+		return true
+	}
+	return false
+}
+
+// Validate checks that the permission is well-formed for its type. Each
+// permission type requires a different combination of fields, for example a
+// bucket-prefix permission must have a Prefix and an Actions value, whereas a
+// policy permission must have a JSON policy document. A *ValidationError
+// describing all problems found is returned, or nil if there are none.
+//
+// Fields which do not belong to the type, such as a Prefix on an all-buckets
+// permission, are only rejected by CreatePermission, since the API may return
+// them with existing permissions, which must remain writable.
+func (p *Permission) Validate() error {
+	return p.validate(false)
+}
+
+// validate implements Validate. When create is set, fields which do not
+// belong to the type are rejected too.
+func (p *Permission) validate(create bool) error {
+	v := &validator{object: "Permission"}
+
+	if strings.TrimSpace(p.Name) == "" {
+		v.add("name", "is required")
+	}
+
+	if p.Actions != "" && !p.Actions.Valid() {
+		v.add("actions", "unknown action "+string(p.Actions))
+	}
+
+	switch p.Type {
+	case "":
+		v.add("type", "is required")
+	case AllBuckets:
+		p.validateActions(v)
+	case BucketPrefix:
+		p.validateActions(v)
+		if p.Prefix == "" {
+			v.add("prefix", "is required for "+string(BucketPrefix)+
+				" permissions")
+		} else if !bucketPrefixRe.MatchString(p.Prefix) {
+			v.add("prefix", "is not a valid bucket name prefix")
+		}
+	case BucketNames:
+		p.validateActions(v)
+		if len(p.Buckets) == 0 {
+			v.add("buckets", "at least one bucket is required for "+
+				string(BucketNames)+" permissions")
+		}
+		v.checkBuckets(p.Buckets)
+	case Policy:
+		p.validatePolicy(v)
+	default:
+		v.add("type", "unknown permission type "+string(p.Type))
+	}
+
+	if create {
+		v.checkTypeFields(p.Type, p.Actions != "", p.Prefix != "",
+			len(p.Buckets) > 0, p.Policy != "")
+	}
+
+	return v.err()
+}
+
+func (p *Permission) validateActions(v *validator) {
+	if p.Actions == "" {
+		v.add("actions", "is required for "+string(p.Type)+" permissions")
+	}
+}
+
+func (p *Permission) validatePolicy(v *validator) {
+	if strings.TrimSpace(p.Policy) == "" {
+		v.addErr("policy", PolicyMissingErrMsg, PolicyMissingErr)
+		return
+	}
+
+	if _, err := ParsePolicyDocument(p.Policy); err != nil {
+		v.add("policy", err.Error())
+	}
+}
+
+// Validate checks that the request names the account and lists at least one
+// permission ID, and that the expiration date, if set, is a timestamp in the
+// future. A *ValidationError describing all problems found is returned, or nil
+// if there are none.
+func (r *CreateServiceAcctReq) Validate() error {
+	v := &validator{object: "CreateServiceAcctReq"}
+
+	if strings.TrimSpace(r.Name) == "" {
+		v.add("name", "is required")
+	}
+
+	if len(r.Permissions) == 0 {
+		v.addErr("permissions", NoPermissionsProvidedErrMsg,
+			ErrNoPermissionsProvided)
+	}
+	v.checkIds("permissions", r.Permissions)
+
+	if r.ExpirationDate != "" {
+		if t, err := r.ExpirationDate.Time(); err != nil {
+			v.add("expirationDate", "must be an RFC 3339 timestamp")
+		} else if !t.After(time.Now()) {
+			v.add("expirationDate", "must be in the future")
+		}
+	}
+
+	return v.err()
+}
+
+// Validate checks the service account definition used to update an existing
+// account. The name is required, while permissions are optional, but must not
+// contain empty or duplicate IDs. A *ValidationError describing all problems
+// found is returned, or nil if there are none.
+func (s *ServiceAcct) Validate() error {
+	v := &validator{object: "ServiceAcct"}
+
+	if strings.TrimSpace(s.Name) == "" {
+		v.add("name", "is required")
+	}
+	v.checkIds("permissions", s.Permissions)
+
+	return v.err()
+}
+
+// Validate checks the fields set in the request. A name, if set, must not be
+// empty, and permissions, if set, must list at least one permission ID. A
+// *ValidationError describing all problems found is returned, or nil if there
+// are none.
+func (r *ServiceAcctUpdateReq) Validate() error {
+	v := &validator{object: "ServiceAcctUpdateReq"}
+
+	if r.Name != nil && strings.TrimSpace(*r.Name) == "" {
+		v.add("name", "must not be empty")
+	}
+
+	if r.Permissions != nil {
+		if len(*r.Permissions) == 0 {
+			v.addErr("permissions", NoPermissionsProvidedErrMsg,
+				ErrNoPermissionsProvided)
+		}
+		v.checkIds("permissions", *r.Permissions)
+	}
+
+	return v.err()
+}
+
+// Validate checks the fields set in the request, each of which must be valid
+// on its own: a name must not be empty, types and actions must be known,
+// bucket names and prefixes well-formed and a policy a valid JSON document.
+// When the request sets the type, the field the type depends on, such as the
+// Prefix of a bucket-prefix permission, must be set too, and fields which do
+// not belong to the type are rejected. A *ValidationError describing all
+// problems found is returned, or nil if there are none.
+func (r *PermissionUpdateReq) Validate() error {
+	v := &validator{object: "PermissionUpdateReq"}
+
+	if r.Name != nil && strings.TrimSpace(*r.Name) == "" {
+		v.add("name", "must not be empty")
+	}
+
+	if r.Type != nil && !r.Type.Valid() {
+		v.add("type", "unknown permission type "+string(*r.Type))
+	}
+
+	if r.Actions != nil && !r.Actions.Valid() {
+		v.add("actions", "unknown action "+string(*r.Actions))
+	}
+
+	if r.Prefix != nil && !bucketPrefixRe.MatchString(*r.Prefix) {
+		v.add("prefix", "is not a valid bucket name prefix")
+	}
+
+	if r.Buckets != nil {
+		if len(*r.Buckets) == 0 {
+			v.add("buckets", "must list at least one bucket")
+		}
+		v.checkBuckets(*r.Buckets)
+	}
+
+	if r.Policy != nil {
+		if _, err := ParsePolicyDocument(*r.Policy); err != nil {
+			v.add("policy", err.Error())
+		}
+	}
+
+	// The fields of the old type are cleared when the type changes, so the
+	// field the new type depends on must be set along with it.
+	if r.Type != nil {
+		switch *r.Type {
+		case BucketPrefix:
+			if r.Prefix == nil {
+				v.add("prefix", "is required for "+string(BucketPrefix)+
+					" permissions")
+			}
+		case BucketNames:
+			if r.Buckets == nil {
+				v.add("buckets", "at least one bucket is required for "+
+					string(BucketNames)+" permissions")
+			}
+		case Policy:
+			if r.Policy == nil || strings.TrimSpace(*r.Policy) == "" {
+				v.addErr("policy", PolicyMissingErrMsg, PolicyMissingErr)
+			}
+		}
+
+		v.checkTypeFields(*r.Type, r.Actions != nil, r.Prefix != nil,
+			r.Buckets != nil, r.Policy != nil)
+	}
+
+	return v.err()
+}
@@ -0,0 +1,174 @@
+package lyveapi
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPermissionValidate(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		name   string
+		perm   Permission
+		create bool
+		fields []string
+	}
+
+	for _, tc := range []testCase{
+		{
+			name: "valid-all-buckets",
+			perm: Permission{Name: "a", Type: AllBuckets, Actions: ReadOnly},
+		},
+		{
+			name:   "bucket-prefix-without-prefix",
+			perm:   Permission{Name: "a", Type: BucketPrefix, Actions: ReadOnly},
+			fields: []string{"prefix"},
+		},
+		{
+			name:   "bucket-names-without-buckets",
+			perm:   Permission{Name: "a", Type: BucketNames, Actions: WriteOnly},
+			fields: []string{"buckets"},
+		},
+		{
+			name: "bucket-names-with-invalid-bucket",
+			perm: Permission{Name: "a", Type: BucketNames, Actions: WriteOnly,
+				Buckets: []string{"Not_A_Bucket"}},
+			fields: []string{"buckets"},
+		},
+		{
+			name:   "unknown-action-and-missing-name",
+			perm:   Permission{Type: AllBuckets, Actions: "read-write"},
+			fields: []string{"name", "actions"},
+		},
+		{
+			name:   "malformed-policy",
+			perm:   Permission{Name: "a", Type: Policy, Policy: `{"Version":`},
+			fields: []string{"policy"},
+		},
+		{
+			name:   "unknown-type",
+			perm:   Permission{Name: "a", Type: "some-buckets"},
+			fields: []string{"type"},
+		},
+		{
+			name: "all-buckets-with-prefix-and-buckets",
+			perm: Permission{Name: "a", Type: AllBuckets, Actions: ReadOnly,
+				Prefix: "logs", Buckets: []string{"alpha"}},
+			create: true,
+			fields: []string{"prefix", "buckets"},
+		},
+		{
+			name: "bucket-names-with-policy",
+			perm: Permission{Name: "a", Type: BucketNames, Actions: ReadOnly,
+				Buckets: []string{"alpha"}, Policy: `{"Statement":[]}`},
+			create: true,
+			fields: []string{"policy"},
+		},
+		{
+			name: "policy-with-actions",
+			perm: Permission{Name: "a", Type: Policy, Actions: ReadOnly,
+				Policy: `{"Statement":[]}`},
+			create: true,
+			fields: []string{"actions"},
+		},
+	} {
+		t.Run(tc.name, func(tt *testing.T) {
+			if tc.create {
+				if err := tc.perm.Validate(); err != nil {
+					tt.Errorf("Expected foreign fields to be accepted by "+
+						"Validate; got %v", err)
+				}
+			}
+
+			err := tc.perm.validate(tc.create)
+			if len(tc.fields) == 0 {
+				if err != nil {
+					tt.Errorf("Expected no error; got %v", err)
+				}
+				return
+			}
+
+			var vErr *ValidationError
+			if !errors.As(err, &vErr) {
+				tt.Fatalf("Expected a *ValidationError; got %v", err)
+			}
+
+			if len(vErr.Fields) != len(tc.fields) {
+				tt.Fatalf("Expected %d field errors; got %v",
+					len(tc.fields), vErr)
+			}
+
+			for i, f := range tc.fields {
+				if vErr.Fields[i].Field != f {
+					tt.Errorf("Expected field %s; got %s",
+						f, vErr.Fields[i].Field)
+				}
+			}
+
+			if !errors.Is(err, ErrValidationFailed) {
+				tt.Error("Expected errors.Is to match ErrValidationFailed")
+			}
+		})
+	}
+}
+
+func TestPermissionValidatePolicyMissing(t *testing.T) {
+	t.Parallel()
+
+	p := Permission{Name: "a", Type: Policy}
+	if err := p.Validate(); !errors.Is(err, PolicyMissingErr) {
+		t.Errorf("Expected errors.Is to match PolicyMissingErr; got %v", err)
+	}
+}
+
+func TestCreateServiceAcctReqValidate(t *testing.T) {
+	t.Parallel()
+
+	req := CreateServiceAcctReq{Name: "alpha"}
+	if err := req.Validate(); !errors.Is(err, ErrNoPermissionsProvided) {
+		t.Errorf("Expected ErrNoPermissionsProvided; got %v", err)
+	}
+
+	req.Permissions = []string{"p1", "p1", ""}
+	var vErr *ValidationError
+	if err := req.Validate(); !errors.As(err, &vErr) || len(vErr.Fields) != 2 {
+		t.Errorf("Expected duplicate and empty ID errors; got %v", err)
+	}
+
+	req.Permissions = []string{"p1", "p2"}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Expected no error; got %v", err)
+	}
+}
+
+func TestForeignFieldsOnlyRejectedOnCreate(t *testing.T) {
+	t.Parallel()
+
+	// The API echoes a prefix with a bucket-names permission.
+	f, client := newFakeApi(t)
+	id := f.addPermission(Permission{Name: "p", Type: BucketNames,
+		Actions: ReadOnly, Buckets: []string{"alpha"}, Prefix: "logs-"})
+
+	fetched, err := client.GetPermission(id)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err = client.UpdatePermission(id, fetched); err != nil {
+		t.Errorf("Expected a fetched permission to be writable; got %v", err)
+	}
+	if stored := f.permission(id); !reflect.DeepEqual(stored, fetched) {
+		t.Errorf("Unexpected stored permission: %+v", stored)
+	}
+
+	fetched.Name = "q"
+	if _, err = client.CreatePermission(fetched); !errors.Is(err,
+		ErrValidationFailed) {
+		t.Errorf("Expected the prefix to be rejected on create; got %v", err)
+	}
+	if n := f.count(http.MethodPost, "/permissions"); n != 0 {
+		t.Errorf("Expected the create not to be sent; got %d", n)
+	}
+}
@@ -0,0 +1,198 @@
+package lyveapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotReady is matched by errors.Is when a wait for a permission or service
+// account to become ready ends before it does, either because the timeout
+// elapsed or the context was cancelled. The error also wraps the context
+// error, such as context.DeadlineExceeded.
+var ErrNotReady = errors.New("object did not become ready")
+
+// Defaults applied to the zero fields of WaitOptions.
+const (
+	DefaultWaitTimeout         = 5 * time.Minute
+	DefaultWaitInitialInterval = 500 * time.Millisecond
+	DefaultWaitMaxInterval     = 15 * time.Second
+	DefaultWaitMultiplier      = 2.0
+)
+
+// WaitOptions controls how WaitPermissionReady and WaitServiceAccountReady
+// poll the API. Zero fields are replaced with the corresponding defaults, and
+// a nil *WaitOptions uses the defaults throughout.
+type WaitOptions struct {
+	// Timeout bounds the total time spent waiting, in addition to any
+	// deadline of the context.
+	Timeout time.Duration
+	// InitialInterval is the delay before the second poll.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between polls.
+	MaxInterval time.Duration
+	// Multiplier is the factor by which the delay grows after each poll.
+	Multiplier float64
+}
+
+func (o *WaitOptions) withDefaults() WaitOptions {
+	var out WaitOptions
+	if o != nil {
+		out = *o
+	}
+
+	if out.Timeout <= 0 {
+		out.Timeout = DefaultWaitTimeout
+	}
+	if out.InitialInterval <= 0 {
+		out.InitialInterval = DefaultWaitInitialInterval
+	}
+	if out.MaxInterval <= 0 {
+		out.MaxInterval = DefaultWaitMaxInterval
+	}
+	if out.MaxInterval < out.InitialInterval {
+		out.MaxInterval = out.InitialInterval
+	}
+	if out.Multiplier < 1 {
+		out.Multiplier = DefaultWaitMultiplier
+	}
+	return out
+}
+
+// poll calls check until it reports done, an error which is not retryable is
+// returned, or the wait ends. Retryable errors, as classified by IsRetryable,
+// are ignored. When created is set, the object was just created and a
+// not-found error is ignored too, since a new object may briefly be
+// unavailable while it is provisioned.
+func (o *WaitOptions) poll(ctx context.Context, created bool,
+	check func(ctx context.Context) (bool, error)) error {
+	opts := o.withDefaults()
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	interval := opts.InitialInterval
+	var lastErr error
+	for {
+		done, err := check(ctx)
+		switch {
+		case err == nil && done:
+			return nil
+		case err != nil && ctx.Err() == nil && !IsRetryable(err) &&
+			!(created && IsNotFound(err)):
+			return err
+		}
+		lastErr = err
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if lastErr != nil {
+				return fmt.Errorf("%w: %w; last error: %v",
+					ErrNotReady, ctx.Err(), lastErr)
+			}
+			return fmt.Errorf("%w: %w", ErrNotReady, ctx.Err())
+		case <-timer.C:
+		}
+
+		interval = time.Duration(float64(interval) * opts.Multiplier)
+		if interval > opts.MaxInterval {
+			interval = opts.MaxInterval
+		}
+	}
+}
+
+// WaitPermissionReady polls the permission with the given ID, backing off
+// exponentially between polls, until its ReadyState is true, and returns it.
+// An error wrapping ErrNotReady is returned if the context is cancelled or the
+// timeout of opts elapses first. Other errors, such as a missing permission,
+// end the wait immediately.
+func (client *Client) WaitPermissionReady(ctx context.Context,
+	permissionId string, opts *WaitOptions) (*Permission, error) {
+	return client.waitPermissionReady(ctx, permissionId, opts, false)
+}
+
+// waitPermissionReady implements WaitPermissionReady. created is set when the
+// permission was just created; see poll.
+func (client *Client) waitPermissionReady(ctx context.Context,
+	permissionId string, opts *WaitOptions, created bool) (*Permission, error) {
+	var perm *Permission
+	err := opts.poll(ctx, created, func(ctx context.Context) (bool, error) {
+		p, err := client.GetPermissionWithContext(ctx, permissionId)
+		if err != nil {
+			return false, err
+		}
+		perm = p
+		return p.ReadyState, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return perm, nil
+}
+
+// WaitServiceAccountReady polls the service account with the given ID,
+// backing off exponentially between polls, until its ReadyState is true, and
+// returns it. An error wrapping ErrNotReady is returned if the context is
+// cancelled or the timeout of opts elapses first. Other errors, such as a
+// missing service account, end the wait immediately.
+func (client *Client) WaitServiceAccountReady(ctx context.Context,
+	svcAcctId string, opts *WaitOptions) (*ServiceAcct, error) {
+	return client.waitServiceAccountReady(ctx, svcAcctId, opts, false)
+}
+
+// waitServiceAccountReady implements WaitServiceAccountReady. created is set
+// when the service account was just created; see poll.
+func (client *Client) waitServiceAccountReady(ctx context.Context,
+	svcAcctId string, opts *WaitOptions, created bool) (*ServiceAcct, error) {
+	var acct *ServiceAcct
+	err := opts.poll(ctx, created, func(ctx context.Context) (bool, error) {
+		a, err := client.GetServiceAccountWithContext(ctx, svcAcctId)
+		if err != nil {
+			return false, err
+		}
+		acct = a
+		return a.ReadyState, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return acct, nil
+}
+
+// CreateOption modifies the behaviour of CreatePermission and
+// CreateServiceAccount.
+type CreateOption func(*createOptions)
+
+type createOptions struct {
+	wait     bool
+	waitCtx  context.Context
+	waitOpts *WaitOptions
+}
+
+func newCreateOptions(opts []CreateOption) *createOptions {
+	o := &createOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WaitUntilReady makes CreatePermission and CreateServiceAccount block until
+// the new object is ready, as WaitPermissionReady and WaitServiceAccountReady
+// do, except that the new object not being found yet is not an error. If the
+// wait fails, the create call returns the created object along
+// with the error, so that the caller learns its ID. A nil ctx is treated as
+// context.Background().
+func WaitUntilReady(ctx context.Context, opts *WaitOptions) CreateOption {
+	return func(o *createOptions) {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		o.wait, o.waitCtx, o.waitOpts = true, ctx, opts
+	}
+}
@@ -0,0 +1,144 @@
+package lyveapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var fastWait = &WaitOptions{
+	Timeout:         2 * time.Second,
+	InitialInterval: time.Millisecond,
+	MaxInterval:     5 * time.Millisecond,
+}
+
+func TestWaitPermissionReady(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	f.pendingReads = 3
+
+	p, err := client.CreatePermission(&Permission{
+		Name: "p", Type: AllBuckets, Actions: ReadOnly})
+	if err != nil || p.ReadyState {
+		t.Fatalf("Unexpected result: %+v, %v", p, err)
+	}
+
+	ready, err := client.WaitPermissionReady(context.Background(), p.Id, fastWait)
+	if err != nil || !ready.ReadyState {
+		t.Fatalf("Unexpected result: %+v, %v", ready, err)
+	}
+
+	if n := f.count(http.MethodGet, "/permissions/"); n != 3 {
+		t.Errorf("Expected 3 polls; got %d", n)
+	}
+
+	_, err = client.WaitPermissionReady(context.Background(), "missing", fastWait)
+	if !errors.Is(err, ErrPermissionNotFound) {
+		t.Errorf("Expected the wait to end on a missing permission; got %v", err)
+	}
+}
+
+func TestWaitServiceAccountReadyTimeout(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	f.pendingReads = 1 << 30
+	p := f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+
+	start := time.Now()
+	resp, err := client.CreateServiceAccount(
+		&CreateServiceAcctReq{Name: "sa", Permissions: []string{p}},
+		WaitUntilReady(context.Background(), &WaitOptions{
+			Timeout:         50 * time.Millisecond,
+			InitialInterval: 5 * time.Millisecond,
+		}))
+
+	if !errors.Is(err, ErrNotReady) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a timeout; got %v", err)
+	}
+	if resp == nil || resp.Id == "" {
+		t.Fatalf("Expected the created account to be returned: %+v", resp)
+	}
+	if f.serviceAccount(resp.Id) == nil {
+		t.Error("Expected the account to be kept after the timeout")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Wait took too long: %v", time.Since(start))
+	}
+}
+
+func TestCreateServiceAccountWaitUntilReady(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	f.pendingReads = 2
+	p := f.addPermission(Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+
+	resp, err := client.CreateServiceAccount(
+		&CreateServiceAcctReq{Name: "sa", Permissions: []string{p}},
+		WaitUntilReady(context.Background(), fastWait))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n := f.count(http.MethodGet, "/service-accounts/"+resp.Id); n != 2 {
+		t.Errorf("Expected 2 polls; got %d", n)
+	}
+}
+
+func TestCreatePermissionWaitUntilVisible(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	f.pendingReads = 1
+
+	// The new permission is not found by the first poll.
+	hidden := 1
+	f.mtx.Lock()
+	f.before = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodGet && hidden > 0 {
+			hidden--
+			fakeError(w, http.StatusNotFound, "PermissionNotFound")
+			return false
+		}
+		return true
+	}
+	f.mtx.Unlock()
+
+	p, err := client.CreatePermission(
+		&Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly},
+		WaitUntilReady(context.Background(), fastWait))
+	if err != nil || !p.ReadyState {
+		t.Fatalf("Unexpected result: %+v, %v", p, err)
+	}
+}
+
+func TestCreatePermissionWithContextCancelled(t *testing.T) {
+	t.Parallel()
+
+	f, client := newFakeApi(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.CreatePermissionWithContext(ctx,
+		&Permission{Name: "p", Type: AllBuckets, Actions: ReadOnly})
+	if !errors.Is(err, context.Canceled) ||
+		f.count(http.MethodPost, "/permissions") != 0 {
+		t.Errorf("Expected the request not to be sent; got %v", err)
+	}
+}
+
+func TestWaitOptionsDefaults(t *testing.T) {
+	t.Parallel()
+
+	var opts *WaitOptions
+	d := opts.withDefaults()
+	if d.Timeout != DefaultWaitTimeout || d.Multiplier != DefaultWaitMultiplier ||
+		d.InitialInterval != DefaultWaitInitialInterval ||
+		d.MaxInterval != DefaultWaitMaxInterval {
+		t.Errorf("Unexpected defaults: %+v", d)
+	}
+}
@@ -32,7 +32,7 @@ func TestNewClient(t *testing.T) {
 	apitest.New().
 		Report(apitest.SequenceDiagram()).
 		Mocks(tokenValidationMock).
-		Handler(authenticationHandler()).
+		Handler(authenticationHandler(t)).
 		Post(mockApiAuthenticationUrl).
 		JSON(mockCredentialBody).
 		Expect(t).
@@ -41,7 +41,7 @@ func TestNewClient(t *testing.T) {
 		End()
 }
 
-func authenticationHandler() *http.ServeMux {
+func authenticationHandler(t *testing.T) *http.ServeMux {
 	var handler = http.NewServeMux()
 	handler.HandleFunc("/v2/auth/token", func(w http.ResponseWriter, r *http.Request) {
 		var token lyveapi.Token
@@ -65,8 +65,8 @@ func authenticationHandler() *http.ServeMux {
 				// The order here is important. First, call the WriteHeader
 				// method to set the http.StatusForbidden response code.
 				// Next, write the necessary JSON payload.
-				w.WriteHeader(err.(*lyveapi.ApiCallFailedError).HttpStatusCode())
-				if _, err = w.Write(err.(*lyveapi.ApiCallFailedError).JSON()); err != nil {
+				w.WriteHeader(apiCallFailedErr(t, err).HttpStatusCode())
+				if _, err = w.Write(apiCallFailedErr(t, err).JSON()); err != nil {
 					w.WriteHeader(http.StatusInternalServerError)
 				}
 				return
@@ -1,6 +1,11 @@
 package lyveapi_test
 
-import "errors"
+import (
+	"errors"
+	"testing"
+
+	"github.com/racktopsystems/lyvecloud/lyveapi"
+)
 
 const (
 	mockAuthTokenUri        = "/auth/token"
@@ -26,3 +31,16 @@ const (
 
 var pathUnmatchedErr = errors.New("no path matched")
 var unexpectedSuccessErr = errors.New("expected function to return a non-nil error")
+
+// apiCallFailedErr extracts the *lyveapi.ApiCallFailedError wrapped by an error
+// returned from the client. The test fails if there is none, rather than
+// responding with a meaningless status.
+func apiCallFailedErr(t *testing.T, err error) *lyveapi.ApiCallFailedError {
+	t.Helper()
+
+	var apiErr *lyveapi.ApiCallFailedError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an *lyveapi.ApiCallFailedError; got %v", err)
+	}
+	return apiErr
+}
@@ -81,7 +81,7 @@ func TestPermissions(t *testing.T) {
 	apitest.New().
 		Report(apitest.SequenceDiagram()).
 		Mocks(permissionsListMock).
-		Handler(permissionsHandler()).
+		Handler(permissionsHandler(t)).
 		Get(mockPermissionsUri).
 		Expect(t).
 		Body(pListRespBody).
@@ -91,7 +91,7 @@ func TestPermissions(t *testing.T) {
 	apitest.New().
 		Report(apitest.SequenceDiagram()).
 		Mocks(permGetByIdMock).
-		Handler(permissionsHandler()).
+		Handler(permissionsHandler(t)).
 		Get(mockPermissionCreateUri).
 		Expect(t).
 		Body(pGetByIdRespBody).
@@ -101,7 +101,7 @@ func TestPermissions(t *testing.T) {
 	apitest.New().
 		Report(apitest.SequenceDiagram()).
 		Mocks(permBadCreatePolicyMock).
-		Handler(permissionsHandler()).
+		Handler(permissionsHandler(t)).
 		Post(mockPermissionsUri).
 		Expect(t).
 		Body(createPermBadPolicyRespJSONObj).
@@ -111,7 +111,7 @@ func TestPermissions(t *testing.T) {
 	apitest.New().
 		Report(apitest.SequenceDiagram()).
 		Mocks(permGoodCreatePolicyMock).
-		Handler(permsCreateUpdateHandler()).
+		Handler(permsCreateUpdateHandler(t)).
 		Post(mockPermissionsUri).
 		Expect(t).
 		Body(pCreateGoodRespBody).
@@ -121,14 +121,14 @@ func TestPermissions(t *testing.T) {
 	apitest.New().
 		Report(apitest.SequenceDiagram()).
 		Mocks(permUpdateMock).
-		Handler(permissionsHandler()).
+		Handler(permissionsHandler(t)).
 		Put(mockPermissionUpdateUri).
 		Expect(t).
 		Status(http.StatusOK).
 		End()
 }
 
-func permissionsHandler() *http.ServeMux {
+func permissionsHandler(t *testing.T) *http.ServeMux {
 	var handler = http.NewServeMux()
 
 	handler.HandleFunc(mockPermissionsUri, func(w http.ResponseWriter, r *http.Request) {
@@ -155,9 +155,7 @@ func permissionsHandler() *http.ServeMux {
 				Name:        "mock-permission-with-bad-policy",
 				Description: "Mock description",
 				Type:        lyveapi.Policy,
-				Prefix:      "pre",
-				Policy:      `{"garbage": "ploicy"}`,
-				Actions:     lyveapi.AllOperations}
+				Policy:      `{"garbage": "ploicy"}`}
 
 			if err := permsBadHttpPost(
 				mockPermissionsUri, req, &permission); err != nil {
@@ -165,7 +163,7 @@ func permissionsHandler() *http.ServeMux {
 				// method to set the http.StatusInternalServerError
 				// response code. Next, write the necessary JSON payload.
 				w.WriteHeader(
-					err.(*lyveapi.ApiCallFailedError).HttpStatusCode())
+					apiCallFailedErr(t, err).HttpStatusCode())
 				_, _ = w.Write([]byte(createPermBadPolicyRespJSONObj))
 				return
 			} else {
@@ -193,9 +191,9 @@ func permissionsHandler() *http.ServeMux {
 	handler.HandleFunc(mockPermissionUpdateUri, func(w http.ResponseWriter, r *http.Request) {
 		var permission lyveapi.Permission
 		req := &lyveapi.Permission{
-			Name:        "mock-permission-with-policy-1",
-			Description: "Mock policy-type permission",
-			Type:        lyveapi.Policy,
+			Name:        "mock-permission-with-buckets-1",
+			Description: "Mock bucket-names-type permission",
+			Type:        lyveapi.BucketNames,
 			Actions:     lyveapi.AllOperations,
 			Buckets:     []string{"alpha-bucket", "beta-bucket"},
 		}
@@ -220,7 +218,7 @@ func permsHttpPut(path string, permission *lyveapi.Permission, response interfac
 	return client.UpdatePermission(permissionId, permission)
 }
 
-func permsCreateUpdateHandler() *http.ServeMux {
+func permsCreateUpdateHandler(t *testing.T) *http.ServeMux {
 	var handler = http.NewServeMux()
 
 	handler.HandleFunc(mockPermissionsUri, func(w http.ResponseWriter, r *http.Request) {
@@ -241,7 +239,7 @@ func permsCreateUpdateHandler() *http.ServeMux {
 				// method to set the http.StatusInternalServerError
 				// response code. Next, write the necessary JSON payload.
 				w.WriteHeader(
-					err.(*lyveapi.ApiCallFailedError).HttpStatusCode())
+					apiCallFailedErr(t, err).HttpStatusCode())
 				_, _ = w.Write([]byte(createPermBadPolicyRespJSONObj))
 				return
 			} else {
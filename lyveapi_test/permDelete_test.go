@@ -32,7 +32,7 @@ func TestPermissionsDelete(t *testing.T) {
 	apitest.New().
 		Report(apitest.SequenceDiagram()).
 		Mocks(permissionDoesNotExist).
-		Handler(deletePermissionHandler()).
+		Handler(deletePermissionHandler(t)).
 		Delete(mockPermissionDelUri1).
 		Expect(t).
 		Body(pDoesNotExistRespJSONObj).
@@ -42,7 +42,7 @@ func TestPermissionsDelete(t *testing.T) {
 	apitest.New().
 		Report(apitest.SequenceDiagram()).
 		Mocks(permissionExists).
-		Handler(deletePermissionHandler()).
+		Handler(deletePermissionHandler(t)).
 		Delete(mockPermissionDelUri2).
 		Expect(t).
 		Status(http.StatusOK).
@@ -54,11 +54,11 @@ type mockDeletePermResp struct {
 	httpStatusCode int
 }
 
-func deletePermissionHandler() *http.ServeMux {
+func deletePermissionHandler(t *testing.T) *http.ServeMux {
 	handler := http.NewServeMux()
 	handler.HandleFunc(mockPermissionDelUri1, func(w http.ResponseWriter, r *http.Request) {
 		errResponse := lyveapi.ApiCallFailedError{}
-		if err := doDelete(r.URL.Path, &errResponse); err != nil {
+		if err := doDelete(t, r.URL.Path, &errResponse); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -77,7 +77,7 @@ func deletePermissionHandler() *http.ServeMux {
 
 	handler.HandleFunc(mockPermissionDelUri2, func(w http.ResponseWriter, r *http.Request) {
 		response := mockDeletePermResp{}
-		if err := doDelete(r.URL.Path, &response); err == nil {
+		if err := doDelete(t, r.URL.Path, &response); err == nil {
 			w.WriteHeader(response.httpStatusCode)
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -89,7 +89,7 @@ func deletePermissionHandler() *http.ServeMux {
 
 }
 
-func doDelete(path string, response interface{}) error {
+func doDelete(t *testing.T, path string, response interface{}) error {
 	var client = &lyveapi.Client{}
 	client.SetApiURL(mockApiEndpointUrl)
 
@@ -100,7 +100,7 @@ func doDelete(path string, response interface{}) error {
 		permissionId := path[len(mockPermissionsUri+"/"):]
 		if err := client.DeletePermission(permissionId); err != nil {
 			*response.(*lyveapi.ApiCallFailedError) =
-				*err.(*lyveapi.ApiCallFailedError)
+				*apiCallFailedErr(t, err)
 		}
 
 	case mockPermissionDelUri2:
@@ -58,7 +58,7 @@ func TestToken(t *testing.T) {
 	apitest.New().
 		Report(apitest.SequenceDiagram()).
 		Mocks(tokenValidationMock).
-		Handler(tokenHandler()).
+		Handler(tokenHandler(t)).
 		Get(mockAuthTokenUri).
 		Expect(t).
 		Body(mockTokenValid).
@@ -69,7 +69,7 @@ func TestToken(t *testing.T) {
 	apitest.New().
 		Report(apitest.SequenceDiagram()).
 		Mocks(tokenAcquisitionMock).
-		Handler(tokenHandler()).
+		Handler(tokenHandler(t)).
 		Post(mockAuthTokenUri).
 		Body(mockTokenAcquisitionReq).
 		Expect(t).
@@ -81,7 +81,7 @@ func TestToken(t *testing.T) {
 	apitest.New().
 		Report(apitest.SequenceDiagram()).
 		Mocks(tokenAcquisitionBadAuthMock).
-		Handler(tokenHandler()).
+		Handler(tokenHandler(t)).
 		Post(mockAuthTokenUri).
 		Body(mockTokenAcquisitionReq).
 		Expect(t).
@@ -90,7 +90,7 @@ func TestToken(t *testing.T) {
 		End()
 }
 
-func tokenHandler() *http.ServeMux {
+func tokenHandler(t *testing.T) *http.ServeMux {
 	var handler = http.NewServeMux()
 	handler.HandleFunc(mockAuthTokenUri, func(w http.ResponseWriter, r *http.Request) {
 		var token lyveapi.Token
@@ -105,8 +105,8 @@ func tokenHandler() *http.ServeMux {
 				// The order here is important. First, call the WriteHeader
 				// method to set the http.StatusForbidden response code.
 				// Next, write the necessary JSON payload.
-				w.WriteHeader(err.(*lyveapi.ApiCallFailedError).HttpStatusCode())
-				if _, err = w.Write(err.(*lyveapi.ApiCallFailedError).JSON()); err != nil {
+				w.WriteHeader(apiCallFailedErr(t, err).HttpStatusCode())
+				if _, err = w.Write(apiCallFailedErr(t, err).JSON()); err != nil {
 					w.WriteHeader(http.StatusInternalServerError)
 				}
 				return